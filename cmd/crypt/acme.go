@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/acme"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/spf13/cobra"
+	"github.com/square/go-jose/v3"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"time"
+)
+
+var (
+	acmeDirectory  string
+	acmeAccountKey = flags.FileRead()
+	acmeTrust      = flags.FileRead()
+	acmeTimeout    time.Duration
+
+	acmeRegisterContact []string
+
+	acmeOrderNames []string
+	acmeOrderURL   string
+	acmeListen     string
+	acmeCSR        = flags.FileRead()
+	acmeOutput     = flags.FileWrite()
+)
+
+var acmeCommand = &cobra.Command{
+	Use:   "acme",
+	Short: "Request certificates from an ACME CA",
+}
+
+var acmeRegisterCommand = &cobra.Command{
+	Use:   "register",
+	Short: "Register an ACME account for the account key",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		client, ctx, cancel, err := newACMEClient()
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		account, err := client.Register(ctx, acmeRegisterContact)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(os.Stdout, "%s %s\n", client.AccountURL, account.Status)
+		return err
+	},
+}
+
+var acmeOrderCommand = &cobra.Command{
+	Use:   "order",
+	Short: "Order a certificate for DNS names and IPs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		client, ctx, cancel, err := newACMEAccountClient()
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		order, err := client.NewOrder(ctx, acme.Identifiers(acmeOrderNames))
+		if err != nil {
+			return err
+		}
+		return writeACMEOrder(order)
+	},
+}
+
+var acmeAuthorizeCommand = &cobra.Command{
+	Use:   "authorize",
+	Short: "Complete the http-01 challenges of an order with a built-in responder",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		client, ctx, cancel, err := newACMEAccountClient()
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		order, err := client.GetOrder(ctx, acmeOrderURL)
+		if err != nil {
+			return err
+		}
+		err = authorizeACMEOrder(ctx, client, order)
+		if err != nil {
+			return err
+		}
+		order, err = client.GetOrder(ctx, acmeOrderURL)
+		if err != nil {
+			return err
+		}
+		return writeACMEOrder(order)
+	},
+}
+
+var acmeFinalizeCommand = &cobra.Command{
+	Use:   "finalize",
+	Short: "Finalize an authorized order with a CSR, and output the certificate chain",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		csr, err := decodeACMECSR()
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		client, ctx, cancel, err := newACMEAccountClient()
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		order, err := client.GetOrder(ctx, acmeOrderURL)
+		if err != nil {
+			return err
+		}
+		return finalizeACMEOrder(ctx, client, order, csr)
+	},
+}
+
+var acmeCertificateCommand = &cobra.Command{
+	Use:   "certificate",
+	Short: "Order, authorize and finalize a certificate for a CSR in one step",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		csr, err := decodeACMECSR()
+		if err != nil {
+			return err
+		}
+		names := acmeOrderNames
+		if len(names) == 0 {
+			names = csrNames(csr)
+		}
+		cmd.SilenceUsage = true
+		client, ctx, cancel, err := newACMEAccountClient()
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		order, err := client.NewOrder(ctx, acme.Identifiers(names))
+		if err != nil {
+			return err
+		}
+		err = authorizeACMEOrder(ctx, client, order)
+		if err != nil {
+			return err
+		}
+		return finalizeACMEOrder(ctx, client, order, csr)
+	},
+}
+
+func init() {
+	acmeCommand.AddCommand(
+		acmeRegisterCommand,
+		acmeOrderCommand,
+		acmeAuthorizeCommand,
+		acmeFinalizeCommand,
+		acmeCertificateCommand,
+	)
+
+	options := acmeCommand.PersistentFlags()
+	options.SortFlags = false
+	options.StringVar(&acmeDirectory, "directory", "", "ACME directory URL")
+	options.VarP(acmeAccountKey, "account-key", "k", "Account private key: PEM, JWK, or JWK set")
+	options.Var(acmeTrust, "trust", "Trust bundle for the ACME server (default system roots)")
+	options.DurationVar(&acmeTimeout, "timeout", 5*time.Minute, "Overall timeout")
+	_ = acmeCommand.MarkPersistentFlagRequired("directory")
+	_ = acmeCommand.MarkPersistentFlagRequired("account-key")
+
+	acmeRegisterCommand.Flags().StringSliceVar(&acmeRegisterContact, "contact", nil, "Contact URL, such as mailto:admin@example.com")
+
+	acmeOrderCommand.Flags().StringSliceVarP(&acmeOrderNames, "name", "d", nil, "DNS name or IP to order")
+	_ = acmeOrderCommand.MarkFlagRequired("name")
+
+	options = acmeAuthorizeCommand.Flags()
+	options.SortFlags = false
+	options.StringVar(&acmeOrderURL, "order", "", "Order URL")
+	options.StringVarP(&acmeListen, "listen", "l", ":80", "Address for the http-01 responder")
+	_ = acmeAuthorizeCommand.MarkFlagRequired("order")
+
+	options = acmeFinalizeCommand.Flags()
+	options.SortFlags = false
+	options.StringVar(&acmeOrderURL, "order", "", "Order URL")
+	options.Var(acmeCSR, "csr", "Certificate signing request")
+	options.VarP(acmeOutput, "output", "o", "Output file for the certificate chain (default stdout)")
+	_ = acmeFinalizeCommand.MarkFlagRequired("order")
+	_ = acmeFinalizeCommand.MarkFlagRequired("csr")
+
+	options = acmeCertificateCommand.Flags()
+	options.SortFlags = false
+	options.Var(acmeCSR, "csr", "Certificate signing request")
+	options.StringSliceVarP(&acmeOrderNames, "name", "d", nil, "DNS name or IP to order (default the CSR names)")
+	options.StringVarP(&acmeListen, "listen", "l", ":80", "Address for the http-01 responder")
+	options.VarP(acmeOutput, "output", "o", "Output file for the certificate chain (default stdout)")
+	_ = acmeCertificateCommand.MarkFlagRequired("csr")
+}
+
+func newACMEClient() (*acme.Client, context.Context, context.CancelFunc, error) {
+	key, err := decodeAccountKey()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if acmeTrust.File() != nil {
+		roots, err := decodeCertPool(acmeTrust)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	client := &acme.Client{
+		DirectoryURL: acmeDirectory,
+		Key:          key,
+		HTTPClient:   &http.Client{Transport: transport, Timeout: time.Minute},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), acmeTimeout)
+	return client, ctx, cancel, nil
+}
+
+// newACMEAccountClient returns a client for the existing account of the
+// account key.
+func newACMEAccountClient() (*acme.Client, context.Context, context.CancelFunc, error) {
+	client, ctx, cancel, err := newACMEClient()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	_, err = client.Account(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	return client, ctx, cancel, nil
+}
+
+func decodeAccountKey() (*jose.JSONWebKey, error) {
+	defer acmeAccountKey.File().Close()
+	decoded, err := jcrypt.KeyEncodings.Decode(acmeAccountKey.File())
+	if err != nil {
+		return nil, err
+	}
+	key, err := jcrypt.SelectKey(decoded, "")
+	if err != nil {
+		return nil, err
+	}
+	if key.IsPublic() {
+		return nil, fmt.Errorf("expected an account private key, got %v", reflect.TypeOf(key.Key))
+	}
+	return key, nil
+}
+
+func decodeACMECSR() (*x509.CertificateRequest, error) {
+	defer acmeCSR.File().Close()
+	csrPem, err := encoding.DecodePEM(acmeCSR.File())
+	if err != nil {
+		return nil, err
+	}
+	csr, ok := csrPem[0].(*x509.CertificateRequest)
+	if !ok {
+		return nil, fmt.Errorf("expected a CSR, got %v", reflect.TypeOf(csrPem[0]))
+	}
+	return csr, nil
+}
+
+func csrNames(csr *x509.CertificateRequest) []string {
+	names := append([]string{}, csr.DNSNames...)
+	for _, ip := range csr.IPAddresses {
+		names = append(names, ip.String())
+	}
+	if len(names) == 0 && csr.Subject.CommonName != "" {
+		names = append(names, csr.Subject.CommonName)
+	}
+	return names
+}
+
+func authorizeACMEOrder(ctx context.Context, client *acme.Client, order *acme.Order) error {
+	listener, err := net.Listen("tcp", acmeListen)
+	if err != nil {
+		return err
+	}
+	responder := acme.NewHTTP01Responder()
+	server := &http.Server{Handler: responder, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Close()
+	return client.AuthorizeHTTP01(ctx, order, responder)
+}
+
+func finalizeACMEOrder(ctx context.Context, client *acme.Client, order *acme.Order, csr *x509.CertificateRequest) error {
+	if order.Status != "ready" && order.Status != "valid" {
+		var err error
+		order, err = client.GetOrder(ctx, order.URL)
+		if err != nil {
+			return err
+		}
+	}
+	if order.Status == "pending" {
+		return errors.New("order is not authorized yet")
+	}
+	if order.Status != "valid" {
+		var err error
+		order, err = client.Finalize(ctx, order, csr)
+		if err != nil {
+			return err
+		}
+	}
+	certs, err := client.Certificate(ctx, order)
+	if err != nil {
+		return err
+	}
+	out := openOutput(acmeOutput)
+	defer out.Close()
+	return encoding.EncodePEM(out, certificateChain(certs[0], certs[1:]))
+}
+
+func writeACMEOrder(order *acme.Order) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		URL string `json:"url"`
+		*acme.Order
+	}{order.URL, order})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var (
+	bundleIntermediates string
+	bundleOutput        string
+	bundleIncludeRoot   bool
+	bundleIncomplete    bool
+)
+
+var bundleCommand = &cobra.Command{
+	Use:   "bundle [file...]",
+	Short: "Order and deduplicate a certificate chain from files, or stdin",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"-"}
+		}
+		var certs []*x509.Certificate
+		for _, path := range args {
+			in, err := openInput([]string{path})
+			if err != nil {
+				return err
+			}
+			decoded, err := decodeCertificateChain(in)
+			_ = in.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			certs = append(certs, decoded...)
+		}
+		pool, err := readCertificateDir(bundleIntermediates)
+		if err != nil {
+			return err
+		}
+
+		chain, err := crypt.OrderChain(certs, pool)
+		if err != nil {
+			return err
+		}
+		last := chain[len(chain)-1]
+		hasRoot := len(chain) > 1 && crypt.IsSelfSigned(last)
+		if !crypt.IsSelfSigned(last) {
+			if !bundleIncomplete {
+				return fmt.Errorf("incomplete chain: issuer %s of %s not found", last.Issuer, last.Subject)
+			}
+			_, err = fmt.Fprintf(os.Stderr, "Warning: chain ends at %s, its issuer %s was not found\n", last.Subject, last.Issuer)
+			if err != nil {
+				return err
+			}
+		}
+		if hasRoot && !bundleIncludeRoot {
+			chain = chain[:len(chain)-1]
+		}
+
+		fullchain := certificateChain(chain[0], chain[1:])
+		if bundleOutput == "" {
+			return encoding.EncodePEM(os.Stdout, fullchain)
+		}
+		err = os.MkdirAll(bundleOutput, 0755)
+		if err != nil {
+			return err
+		}
+		err = encoding.WritePEMFile(filepath.Join(bundleOutput, "fullchain.pem"), fullchain, 0644)
+		if err != nil {
+			return err
+		}
+		if len(fullchain) == 1 {
+			return nil
+		}
+		return encoding.WritePEMFile(filepath.Join(bundleOutput, "chain.pem"), fullchain[1:], 0644)
+	},
+}
+
+func init() {
+	options := bundleCommand.Flags()
+	options.SortFlags = false
+	options.StringVarP(&bundleIntermediates, "intermediates", "I", "", "Directory of certificates to take missing issuers from")
+	options.StringVarP(&bundleOutput, "output", "o", "", "Directory to write fullchain.pem, and chain.pem if there are issuers (default fullchain to stdout)")
+	options.BoolVar(&bundleIncludeRoot, "include-root", false, "Include the self-signed root")
+	options.BoolVar(&bundleIncomplete, "allow-incomplete", false, "Output a chain that does not end at a self-signed root")
+}
+
+// readCertificateDir reads every PEM or DER certificate in dir, skipping
+// files that are not certificates.
+func readCertificateDir(dir string) ([]*x509.Certificate, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, encoding.ExtractCertificates(data)...)
+	}
+	return certs, nil
+}
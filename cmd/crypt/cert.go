@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"time"
+)
+
+var (
+	certParent     = flags.FileRead()
+	certSigningKey = flags.FileRead()
+	certExpiry     flags.Time
+	certIsCA       bool
+	certPolicy     = flags.FileRead()
+
+	certPermittedDNS          []string
+	certExcludedDNS           []string
+	certPermittedIPs          []string
+	certExcludedIPs           []string
+	certPermittedEmails       []string
+	certExcludedEmails        []string
+	certPermittedURIs         []string
+	certExcludedURIs          []string
+	certConstraintsCritical   bool
+	certPolicies              []string
+	certCRLDistributionPoints []string
+	certOCSPServers           []string
+	certCAIssuers             []string
+	certMustStaple            bool
+)
+
+var certCommand = &cobra.Command{
+	Use:   "cert",
+	Short: "Generate a certificate given a CSR on stdin",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		csrPem, err := encoding.DecodePEM(os.Stdin)
+		if err != nil {
+			return err
+		}
+		csr, ok := csrPem[0].(*x509.CertificateRequest)
+		if !ok {
+			return fmt.Errorf("expected a CSR, got %v", reflect.TypeOf(csrPem[0]))
+		}
+		// Check the signature before warning about anything the CSR asks for.
+		err = csr.CheckSignature()
+		if err != nil {
+			return fmt.Errorf("invalid CSR signature: %w", err)
+		}
+		issuer, err := certificateIssuer()
+		if err != nil {
+			return err
+		}
+		extensions, err := certificateExtensions()
+		if err != nil {
+			return err
+		}
+		policy, err := certificatePolicy()
+		if err != nil {
+			return err
+		}
+		_, stripped := policy.FilterExtensions(csr)
+		for _, extension := range stripped {
+			_, err = fmt.Fprintf(os.Stderr, "Warning: stripped requested extension %v\n", extension.Id)
+			if err != nil {
+				return err
+			}
+		}
+		cert, err := crypt.IssueCertificate(csr, issuer, &crypt.CertificateOptions{
+			NotAfter:   certificateExpiry(),
+			IsCA:       certIsCA,
+			Extensions: *extensions,
+			Policy:     policy,
+		})
+		if err != nil {
+			return err
+		}
+		return encoding.EncodePEM(os.Stdout, cert)
+	},
+}
+
+func init() {
+	options := certCommand.Flags()
+	options.SortFlags = false
+	options.VarP(certParent, "parent", "p", "Parent certificate (default self-signed)")
+	options.VarP(certSigningKey, "key", "k", "Certificate signing key")
+	options.VarP(&certExpiry, "expires", "e", "Certificate expiry (default \"8760h\")")
+	options.BoolVar(&certIsCA, "ca", false, "Generate a CA certificate")
+	options.Var(certPolicy, "issuance-policy", "JSON issuance policy file (default honor only requested SANs)")
+	options.StringSliceVar(&certPermittedDNS, "permit-dns", nil, "Permitted DNS name constraint")
+	options.StringSliceVar(&certExcludedDNS, "exclude-dns", nil, "Excluded DNS name constraint")
+	options.StringSliceVar(&certPermittedIPs, "permit-ip", nil, "Permitted IP range name constraint")
+	options.StringSliceVar(&certExcludedIPs, "exclude-ip", nil, "Excluded IP range name constraint")
+	options.StringSliceVar(&certPermittedEmails, "permit-email", nil, "Permitted email name constraint")
+	options.StringSliceVar(&certExcludedEmails, "exclude-email", nil, "Excluded email name constraint")
+	options.StringSliceVar(&certPermittedURIs, "permit-uri", nil, "Permitted URI domain name constraint")
+	options.StringSliceVar(&certExcludedURIs, "exclude-uri", nil, "Excluded URI domain name constraint")
+	options.BoolVar(&certConstraintsCritical, "name-constraints-critical", true, "Mark name constraints critical")
+	options.StringSliceVar(&certPolicies, "certificate-policy", nil, "Certificate policy OID")
+	options.StringSliceVar(&certCRLDistributionPoints, "crl-url", nil, "CRL distribution point URL")
+	options.StringSliceVar(&certOCSPServers, "ocsp-url", nil, "OCSP responder URL")
+	options.StringSliceVar(&certCAIssuers, "ca-issuers-url", nil, "CA issuers URL")
+	options.BoolVar(&certMustStaple, "must-staple", false, "Require OCSP stapling")
+
+	_ = certCommand.MarkFlagRequired("key")
+}
+
+func certificateIssuer() (*crypt.Issuer, error) {
+	key, err := decodeSigningKey(certSigningKey.File())
+	if err != nil {
+		return nil, err
+	}
+	parent, err := certificateParent()
+	if err != nil {
+		return nil, err
+	}
+	return &crypt.Issuer{Certificate: parent, Key: key}, nil
+}
+
+func certificateParent() (*x509.Certificate, error) {
+	if certParent.File() == nil {
+		return nil, nil
+	}
+	defer certParent.File().Close()
+	parentPem, err := encoding.DecodePEM(certParent.File())
+	if err != nil {
+		return nil, err
+	}
+	parent, ok := parentPem[0].(*x509.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("expected parent to be a certificate, got %v", reflect.TypeOf(parentPem[0]))
+	}
+	return parent, nil
+}
+
+func certificatePolicy() (*crypt.IssuancePolicy, error) {
+	if certPolicy.File() == nil {
+		return crypt.DefaultIssuancePolicy, nil
+	}
+	defer certPolicy.File().Close()
+	data, err := ioutil.ReadAll(certPolicy.File())
+	if err != nil {
+		return nil, err
+	}
+	return crypt.ParseIssuancePolicy(data)
+}
+
+func certificateExtensions() (*crypt.Extensions, error) {
+	policies := make([]asn1.ObjectIdentifier, len(certPolicies))
+	for i, policy := range certPolicies {
+		oid, err := crypt.ParseOID(policy)
+		if err != nil {
+			return nil, err
+		}
+		policies[i] = oid
+	}
+	permittedIPs, err := parseIPRanges(certPermittedIPs)
+	if err != nil {
+		return nil, err
+	}
+	excludedIPs, err := parseIPRanges(certExcludedIPs)
+	if err != nil {
+		return nil, err
+	}
+	return &crypt.Extensions{
+		NameConstraints: crypt.NameConstraints{
+			Critical:                certConstraintsCritical,
+			PermittedDNSDomains:     certPermittedDNS,
+			ExcludedDNSDomains:      certExcludedDNS,
+			PermittedIPRanges:       permittedIPs,
+			ExcludedIPRanges:        excludedIPs,
+			PermittedEmailAddresses: certPermittedEmails,
+			ExcludedEmailAddresses:  certExcludedEmails,
+			PermittedURIDomains:     certPermittedURIs,
+			ExcludedURIDomains:      certExcludedURIs,
+		},
+		Policies:               policies,
+		CRLDistributionPoints:  certCRLDistributionPoints,
+		OCSPServers:            certOCSPServers,
+		IssuingCertificateURLs: certCAIssuers,
+		MustStaple:             certMustStaple,
+	}, nil
+}
+
+func parseIPRanges(values []string) ([]*net.IPNet, error) {
+	ranges := make([]*net.IPNet, len(values))
+	for i, value := range values {
+		_, ipRange, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		ranges[i] = ipRange
+	}
+	return ranges, nil
+}
+
+func certificateExpiry() time.Time {
+	if certExpiry > 0 {
+		return time.Unix(int64(certExpiry), 0)
+	}
+	return time.Time{}
+}
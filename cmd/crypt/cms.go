@@ -0,0 +1,313 @@
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/cms"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+)
+
+var (
+	cmsSignKey      = flags.FileRead()
+	cmsSignCert     = flags.FileRead()
+	cmsSignHash     string
+	cmsSignDetached bool
+	cmsSignPEM      bool
+	cmsSignOutput   = flags.FileWrite()
+
+	cmsVerifyContent  = flags.FileRead()
+	cmsVerifyTrust    = flags.FileRead()
+	cmsVerifyInsecure bool
+	cmsVerifyOutput   = flags.FileWrite()
+
+	cmsEncryptRecipients []string
+	cmsEncryptPEM        bool
+	cmsEncryptOutput     = flags.FileWrite()
+
+	cmsDecryptKey    = flags.FileRead()
+	cmsDecryptCert   = flags.FileRead()
+	cmsDecryptOutput string
+)
+
+var cmsCommand = &cobra.Command{
+	Use:   "cms",
+	Short: "Create and verify CMS (PKCS #7) signed and enveloped data",
+}
+
+var cmsSignCommand = &cobra.Command{
+	Use:   "sign [file]",
+	Short: "Generate CMS signed data given a file, or data on stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := decodeSigningKey(cmsSignKey.File())
+		if err != nil {
+			return err
+		}
+		certs, err := decodeCertificates(cmsSignCert)
+		if err != nil {
+			return err
+		}
+		opts := &cms.SignOptions{
+			Detached:     cmsSignDetached,
+			Certificates: certs[1:],
+		}
+		if cmsSignHash != "" {
+			opts.Hash, err = parseCMSHash(cmsSignHash)
+			if err != nil {
+				return err
+			}
+		}
+		content, err := readInput(args)
+		if err != nil {
+			return err
+		}
+
+		signed, err := cms.Sign(content, certs[0], key, opts)
+		if err != nil {
+			return err
+		}
+		return writeCMS(cmsSignOutput, signed, cmsSignPEM)
+	},
+}
+
+var cmsVerifyCommand = &cobra.Command{
+	Use:   "verify [file]",
+	Short: "Verify CMS signed data given a file, or on stdin, and output its content",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cmsVerifyTrust.File() == nil && !cmsVerifyInsecure {
+			return errors.New("--trust is required, or --insecure-skip-chain to only check signatures")
+		}
+		data, err := readInput(args)
+		if err != nil {
+			return err
+		}
+		sd, err := cms.ParseSignedData(decodeCMS(data))
+		if err != nil {
+			return err
+		}
+		opts := &cms.VerifyOptions{}
+		if cmsVerifyTrust.File() != nil {
+			opts.Roots, err = decodeCertPool(cmsVerifyTrust)
+			if err != nil {
+				return err
+			}
+		}
+		var content []byte
+		if cmsVerifyContent.File() != nil {
+			defer cmsVerifyContent.File().Close()
+			content, err = ioutil.ReadAll(cmsVerifyContent.File())
+			if err != nil {
+				return err
+			}
+		}
+
+		signers, err := sd.Verify(content, opts)
+		if err != nil {
+			return err
+		}
+		message := "Verified signer: %s\n"
+		if opts.Roots == nil {
+			message = "Signature valid, signer NOT verified: %s\n"
+		}
+		for _, signer := range signers {
+			_, err = fmt.Fprintf(os.Stderr, message, signer.Subject)
+			if err != nil {
+				return err
+			}
+		}
+		if sd.Content == nil {
+			return nil
+		}
+		out := openOutput(cmsVerifyOutput)
+		defer out.Close()
+		_, err = out.Write(sd.Content)
+		return err
+	},
+}
+
+var cmsEncryptCommand = &cobra.Command{
+	Use:   "encrypt [file]",
+	Short: "Generate CMS enveloped data given a file, or data on stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recipients := make([]*x509.Certificate, len(cmsEncryptRecipients))
+		for i, path := range cmsEncryptRecipients {
+			chain, err := decodePEMFile(path)
+			if err != nil {
+				return err
+			}
+			cert, ok := chain[0].(*x509.Certificate)
+			if !ok {
+				return fmt.Errorf("expected a recipient certificate, got %v", reflect.TypeOf(chain[0]))
+			}
+			recipients[i] = cert
+		}
+		content, err := readInput(args)
+		if err != nil {
+			return err
+		}
+
+		enveloped, err := cms.Encrypt(content, recipients)
+		if err != nil {
+			return err
+		}
+		return writeCMS(cmsEncryptOutput, enveloped, cmsEncryptPEM)
+	},
+}
+
+var cmsDecryptCommand = &cobra.Command{
+	Use:   "decrypt [file]",
+	Short: "Decrypt CMS enveloped data given a file, or on stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := decodeSigningKey(cmsDecryptKey.File())
+		if err != nil {
+			return err
+		}
+		decrypter, ok := key.(crypto.Decrypter)
+		if !ok {
+			return fmt.Errorf("expected a decryption key, got %v", reflect.TypeOf(key))
+		}
+		certs, err := decodeCertificates(cmsDecryptCert)
+		if err != nil {
+			return err
+		}
+		data, err := readInput(args)
+		if err != nil {
+			return err
+		}
+
+		content, err := cms.Decrypt(decodeCMS(data), certs[0], decrypter)
+		if err != nil {
+			return err
+		}
+		if cmsDecryptOutput == "" {
+			_, err = os.Stdout.Write(content)
+			return err
+		}
+		return writePrivateFile(cmsDecryptOutput, func(out io.Writer) error {
+			_, err := out.Write(content)
+			return err
+		})
+	},
+}
+
+func init() {
+	cmsCommand.AddCommand(
+		cmsSignCommand,
+		cmsVerifyCommand,
+		cmsEncryptCommand,
+		cmsDecryptCommand,
+	)
+
+	options := cmsSignCommand.Flags()
+	options.SortFlags = false
+	options.VarP(cmsSignKey, "key", "k", "Signing key")
+	options.VarP(cmsSignCert, "cert", "c", "Signer certificate, followed by any chain to embed")
+	options.StringVar(&cmsSignHash, "hash", "sha256", "Hash: sha256, sha384, or sha512")
+	options.BoolVarP(&cmsSignDetached, "detached", "d", false, "Omit the content from the signed data")
+	options.BoolVar(&cmsSignPEM, "pem", false, "Output PEM instead of DER")
+	options.VarP(cmsSignOutput, "output", "o", "Output file (default stdout)")
+	_ = cmsSignCommand.MarkFlagRequired("key")
+	_ = cmsSignCommand.MarkFlagRequired("cert")
+
+	options = cmsVerifyCommand.Flags()
+	options.SortFlags = false
+	options.Var(cmsVerifyContent, "content", "Content of a detached signature")
+	options.Var(cmsVerifyTrust, "trust", "Trusted root bundle to validate signer certificates against")
+	options.BoolVar(&cmsVerifyInsecure, "insecure-skip-chain", false, "Check signatures without validating signer certificates")
+	options.VarP(cmsVerifyOutput, "output", "o", "Output file (default stdout)")
+
+	options = cmsEncryptCommand.Flags()
+	options.SortFlags = false
+	options.StringArrayVarP(&cmsEncryptRecipients, "recipient", "r", nil, "Recipient certificate file")
+	options.BoolVar(&cmsEncryptPEM, "pem", false, "Output PEM instead of DER")
+	options.VarP(cmsEncryptOutput, "output", "o", "Output file (default stdout)")
+	_ = cmsEncryptCommand.MarkFlagRequired("recipient")
+
+	options = cmsDecryptCommand.Flags()
+	options.SortFlags = false
+	options.VarP(cmsDecryptKey, "key", "k", "Recipient private key")
+	options.VarP(cmsDecryptCert, "cert", "c", "Recipient certificate")
+	options.StringVarP(&cmsDecryptOutput, "output", "o", "", "Output file, readable only by the owner (default stdout)")
+	_ = cmsDecryptCommand.MarkFlagRequired("key")
+	_ = cmsDecryptCommand.MarkFlagRequired("cert")
+}
+
+func parseCMSHash(name string) (crypto.Hash, error) {
+	switch name {
+	case "sha256":
+		return crypto.SHA256, nil
+	case "sha384":
+		return crypto.SHA384, nil
+	case "sha512":
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported hash: %s", name)
+	}
+}
+
+func decodeCertificates(file *flags.File) ([]*x509.Certificate, error) {
+	chain, err := decodePEMFlag(file)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for _, item := range chain {
+		if cert, ok := item.(*x509.Certificate); ok {
+			certs = append(certs, cert)
+		}
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("expected a certificate")
+	}
+	return certs, nil
+}
+
+func decodeCertPool(file *flags.File) (*x509.CertPool, error) {
+	certs, err := decodeCertificates(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+func decodeCMS(data []byte) []byte {
+	block, _ := pem.Decode(data)
+	if block != nil && (block.Type == "CMS" || block.Type == "PKCS7") {
+		return block.Bytes
+	}
+	return data
+}
+
+func writeCMS(output *flags.File, data []byte, encodePEM bool) error {
+	out := openOutput(output)
+	defer out.Close()
+	if encodePEM {
+		return pem.Encode(out, &pem.Block{Type: "CMS", Bytes: data})
+	}
+	_, err := out.Write(data)
+	return err
+}
+
+func readInput(args []string) ([]byte, error) {
+	in, err := openInput(args)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return ioutil.ReadAll(in)
+}
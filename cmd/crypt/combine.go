@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/pem"
+	"fmt"
+	"github.com/credding/crypt/pkg/shamir"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+)
+
+var (
+	combineOutput string
+)
+
+var combineCommand = &cobra.Command{
+	Use:   "combine [share file...]",
+	Short: "Reconstruct a secret from share files, or shares on stdin",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"-"}
+		}
+		var shares []*shamir.Share
+		for _, path := range args {
+			decoded, err := readShares(path)
+			if err != nil {
+				return err
+			}
+			shares = append(shares, decoded...)
+		}
+
+		secret, err := shamir.Combine(shares)
+		if err != nil {
+			return err
+		}
+		if combineOutput == "" {
+			_, err = os.Stdout.Write(secret)
+			return err
+		}
+		return writeSecret(combineOutput, secret)
+	},
+}
+
+func init() {
+	options := combineCommand.Flags()
+	options.SortFlags = false
+	options.StringVarP(&combineOutput, "output", "o", "", "New output file, readable only by the owner (default stdout)")
+}
+
+func writeSecret(path string, secret []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(secret)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func readShares(path string) ([]*shamir.Share, error) {
+	in, err := openInput([]string{path})
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []*shamir.Share
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		share, err := shamir.ParsePEM(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		shares = append(shares, share)
+	}
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("%s: no shares found", path)
+	}
+	return shares, nil
+}
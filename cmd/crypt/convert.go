@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/spf13/cobra"
+	"github.com/square/go-jose/v3"
+	"os"
+	"reflect"
+)
+
+var convertTo string
+
+var convertFormats = map[string]bool{
+	encoding.PKCS1: true,
+	encoding.PKCS8: true,
+	encoding.SEC1:  true,
+	encoding.PKIX:  true,
+	"der":          true,
+	"jwk":          true,
+}
+
+var convertEncodings = encoding.Encodings{
+	encoding.PEM,
+	encoding.JWKs,
+	encoding.JWK,
+	encoding.DER,
+}
+
+var convertCommand = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert a key, certificate, or CSR on stdin between PEM, DER, and JWK encodings",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !convertFormats[convertTo] {
+			return fmt.Errorf("unsupported format: %s", convertTo)
+		}
+		decoded, err := convertEncodings.Decode(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		if jwks, ok := decoded.(*jose.JSONWebKeySet); ok && convertTo == "jwk" {
+			return json.NewEncoder(os.Stdout).Encode(jwks)
+		}
+		chain, err := convertChain(decoded)
+		if err != nil {
+			return err
+		}
+
+		switch convertTo {
+		case "jwk":
+			jwk, err := jcrypt.PEMChainToJWK(chain)
+			if err != nil {
+				return err
+			}
+			return json.NewEncoder(os.Stdout).Encode(jwk)
+		case "der":
+			if len(chain) != 1 {
+				return fmt.Errorf("der holds a single item, got %d", len(chain))
+			}
+			der, err := encoding.MarshalDER(chain[0], "")
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(der)
+			return err
+		default:
+			return encoding.EncodePEMFormat(os.Stdout, chain, convertTo)
+		}
+	},
+}
+
+func init() {
+	options := convertCommand.Flags()
+	options.SortFlags = false
+	options.StringVarP(&convertTo, "to", "t", "", "Output format: pkcs1, pkcs8, sec1, pkix, der, or jwk")
+
+	_ = convertCommand.MarkFlagRequired("to")
+}
+
+// convertChain returns the keys and certificates of decoded input as a PEM
+// chain, each JWK followed by its certificate chain.
+func convertChain(decoded interface{}) (encoding.PEMChain, error) {
+	switch decoded.(type) {
+	case encoding.PEMChain:
+		return decoded.(encoding.PEMChain), nil
+	case *jose.JSONWebKey:
+		return jwkChain(decoded.(*jose.JSONWebKey))
+	case *jose.JSONWebKeySet:
+		var chain encoding.PEMChain
+		for i := range decoded.(*jose.JSONWebKeySet).Keys {
+			keyChain, err := jwkChain(&decoded.(*jose.JSONWebKeySet).Keys[i])
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, keyChain...)
+		}
+		return chain, nil
+	default:
+		return nil, fmt.Errorf("unsupported input type: %v", reflect.TypeOf(decoded))
+	}
+}
+
+func jwkChain(jwk *jose.JSONWebKey) (encoding.PEMChain, error) {
+	if _, ok := jwk.Key.([]byte); ok {
+		return nil, fmt.Errorf("unsupported key type: %v", reflect.TypeOf(jwk.Key))
+	}
+	chain := encoding.PEMChain{jwk.Key}
+	for _, cert := range jwk.Certificates {
+		chain = append(chain, cert)
+	}
+	return chain, nil
+}
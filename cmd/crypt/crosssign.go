@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"reflect"
+	"time"
+)
+
+var (
+	crossSignParent        = flags.FileRead()
+	crossSignSigningKey    = flags.FileRead()
+	crossSignExpiry        flags.Time
+	crossSignChain         = flags.FileWrite()
+	crossSignOriginalChain = flags.FileWrite()
+)
+
+var crossSignCommand = &cobra.Command{
+	Use:   "cross-sign",
+	Short: "Cross-sign a CA certificate, and its chain, on stdin under a new issuer",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		original, err := decodeCertificateChain(os.Stdin)
+		if err != nil {
+			return err
+		}
+		key, err := decodeSigningKey(crossSignSigningKey.File())
+		if err != nil {
+			return err
+		}
+		defer crossSignParent.File().Close()
+		issuerChain, err := decodeCertificateChain(crossSignParent.File())
+		if err != nil {
+			return err
+		}
+
+		var notAfter time.Time
+		if crossSignExpiry > 0 {
+			notAfter = time.Unix(int64(crossSignExpiry), 0)
+		}
+		crossSigned, err := crypt.CrossSign(original[0], &crypt.Issuer{Certificate: issuerChain[0], Key: key}, &crypt.CertificateOptions{
+			NotAfter: notAfter,
+		})
+		if err != nil {
+			return err
+		}
+
+		if crossSignChain.File() != nil {
+			defer crossSignChain.File().Close()
+			err = encoding.EncodePEM(crossSignChain.File(), certificateChain(crossSigned, issuerChain))
+			if err != nil {
+				return err
+			}
+		}
+		if crossSignOriginalChain.File() != nil {
+			defer crossSignOriginalChain.File().Close()
+			err = encoding.EncodePEM(crossSignOriginalChain.File(), certificateChain(original[0], original[1:]))
+			if err != nil {
+				return err
+			}
+		}
+		return encoding.EncodePEM(os.Stdout, crossSigned)
+	},
+}
+
+func init() {
+	options := crossSignCommand.Flags()
+	options.SortFlags = false
+	options.VarP(crossSignParent, "parent", "p", "New issuer certificate, and its chain")
+	options.VarP(crossSignSigningKey, "key", "k", "New issuer signing key")
+	options.VarP(&crossSignExpiry, "expires", "e", "Certificate expiry (default the certificate's, or issuer's if sooner)")
+	options.Var(crossSignChain, "chain", "Write the chain to the new issuer")
+	options.Var(crossSignOriginalChain, "original-chain", "Write the chain to the original issuer")
+
+	_ = crossSignCommand.MarkFlagRequired("parent")
+	_ = crossSignCommand.MarkFlagRequired("key")
+}
+
+func decodeCertificateChain(file io.Reader) ([]*x509.Certificate, error) {
+	chainPem, err := encoding.DecodePEM(file)
+	if err != nil {
+		return nil, err
+	}
+	chain := make([]*x509.Certificate, len(chainPem))
+	for i, item := range chainPem {
+		cert, ok := item.(*x509.Certificate)
+		if !ok {
+			return nil, fmt.Errorf("expected a certificate, got %v", reflect.TypeOf(item))
+		}
+		chain[i] = cert
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return chain, nil
+}
+
+func certificateChain(cert *x509.Certificate, issuers []*x509.Certificate) encoding.PEMChain {
+	chain := encoding.PEMChain{cert}
+	for _, issuer := range issuers {
+		chain = append(chain, issuer)
+	}
+	return chain
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/spf13/cobra"
+	"os"
+	"reflect"
+)
+
+var (
+	csrSigningKey = flags.FileRead()
+	csrCommonName string
+	csrDnsNames   []string
+)
+
+var csrCommand = &cobra.Command{
+	Use: "csr",
+	Short: "Generate a certificate signing request (CSR)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := decodeSigningKey(csrSigningKey.File())
+		if err != nil {
+			return err
+		}
+		csr, err := crypt.CreateCertificateRequest(key, &crypt.CertificateRequestOptions{
+			CommonName: csrCommonName,
+			DNSNames:   csrDnsNames,
+		})
+		if err != nil {
+			return err
+		}
+		return encoding.EncodePEM(os.Stdout, csr)
+	},
+}
+
+func init() {
+	options := csrCommand.Flags()
+	options.SortFlags = false
+	options.VarP(csrSigningKey, "key", "k", "Certificate request signing key")
+	options.StringVarP(&csrCommonName, "common-name", "n", "localhost", "Subject common name")
+	options.StringSliceVarP(&csrDnsNames, "dns-name", "d", nil, "SAN DNS name")
+
+	_ = csrCommand.MarkFlagRequired("key")
+}
+
+func decodeSigningKey(keyFile *os.File) (crypto.Signer, error) {
+	defer keyFile.Close()
+	keyPem, err := encoding.DecodePEM(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := keyPem[0].(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("expected a private key, got %v", reflect.TypeOf(keyPem[0]))
+	}
+	return key, nil
+}
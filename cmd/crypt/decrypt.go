@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto"
+	"github.com/credding/crypt/pkg/stream"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var (
+	decryptKeys   []string
+	decryptOutput string
+)
+
+var decryptCommand = &cobra.Command{
+	Use:   "decrypt [file]",
+	Short: "Decrypt a file, or data on stdin, encrypted with crypt encrypt",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		identities, err := decodeIdentities(decryptKeys)
+		if err != nil {
+			return err
+		}
+		in, err := openInput(args)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		reader, err := stream.Decrypt(in, identities)
+		if err != nil {
+			return err
+		}
+		if decryptOutput == "" {
+			_, err = io.Copy(os.Stdout, reader)
+			return err
+		}
+		return writePrivateFile(decryptOutput, func(out io.Writer) error {
+			_, err := io.Copy(out, reader)
+			return err
+		})
+	},
+}
+
+func init() {
+	options := decryptCommand.Flags()
+	options.SortFlags = false
+	options.StringArrayVarP(&decryptKeys, "key", "k", nil, "Private key file")
+	options.StringVarP(&decryptOutput, "output", "o", "", "Output file, readable only by the owner (default stdout)")
+
+	_ = decryptCommand.MarkFlagRequired("key")
+}
+
+// writePrivateFile writes a file readable only by the owner through a
+// temporary file, renamed into place only once write succeeds, so a failed
+// decryption leaves no partial plaintext behind.
+func writePrivateFile(path string, write func(out io.Writer) error) error {
+	temp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	err = write(temp)
+	closeErr := temp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(temp.Name())
+	}
+	return err
+}
+
+func decodeIdentities(paths []string) ([]crypto.PrivateKey, error) {
+	identities := make([]crypto.PrivateKey, 0, len(paths))
+	for _, path := range paths {
+		chain, err := decodePEMFile(path)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, chain[0])
+	}
+	return identities, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	devCertCADir  string
+	devCertOutput string
+	devCertExpiry flags.Time
+)
+
+var devCertCommand = &cobra.Command{
+	Use:   "dev-cert name...",
+	Short: "Issue a development certificate for hostnames and IPs from a local CA",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		caDir := devCertCADir
+		if caDir == "" {
+			var err error
+			caDir, err = crypt.DevCADir()
+			if err != nil {
+				return err
+			}
+		}
+		issuer, created, err := crypt.LoadDevCA(caDir)
+		if err != nil {
+			return err
+		}
+		if created {
+			_, err = fmt.Fprintf(os.Stderr, "Created development CA, trust %s to use it\n", crypt.DevCACertificatePath(caDir))
+			if err != nil {
+				return err
+			}
+		}
+
+		var notAfter time.Time
+		if devCertExpiry > 0 {
+			notAfter = time.Unix(int64(devCertExpiry), 0)
+		}
+		key, cert, err := crypt.IssueDevCertificate(issuer, args, notAfter)
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(devCertOutput, 0755)
+		if err != nil {
+			return err
+		}
+		err = encoding.WritePEMFile(filepath.Join(devCertOutput, "key.pem"), key, 0600)
+		if err != nil {
+			return err
+		}
+		err = encoding.WritePEMFile(filepath.Join(devCertOutput, "cert.pem"), cert, 0644)
+		if err != nil {
+			return err
+		}
+		return encoding.WritePEMFile(filepath.Join(devCertOutput, "fullchain.pem"), encoding.PEMChain{cert, issuer.Certificate}, 0644)
+	},
+}
+
+func init() {
+	options := devCertCommand.Flags()
+	options.SortFlags = false
+	options.StringVar(&devCertCADir, "ca-dir", "", "Development CA directory (default under the user config directory)")
+	options.StringVarP(&devCertOutput, "output", "o", ".", "Directory to write cert.pem, key.pem and fullchain.pem")
+	options.VarP(&devCertExpiry, "expires", "e", "Certificate expiry (default \"19800h\")")
+}
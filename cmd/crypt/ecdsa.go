@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var (
+	ecdsaCurve string
+)
+
+var ecdsaCommand = &cobra.Command{
+	Use:   "ecdsa",
+	Short: "Generate an ECDSA key",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := crypt.GenerateECDSAKey(ecdsaCurve)
+		if err != nil {
+			return err
+		}
+
+		return encoding.EncodePEM(os.Stdout, key)
+	},
+}
+
+func init() {
+	ecdsaCommand.Flags().StringVarP(&ecdsaCurve, "curve", "c", "P-256", "Curve")
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var ed25519Command = &cobra.Command{
+	Use:   "ed25519",
+	Short: "Generate an Ed25519 key",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := crypt.GenerateEd25519Key()
+		if err != nil {
+			return err
+		}
+
+		return encoding.EncodePEM(os.Stdout, key)
+	},
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/credding/crypt/pkg/stream"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+var (
+	encryptRecipients []string
+	encryptOutput     = flags.FileWrite()
+)
+
+var encryptCommand = &cobra.Command{
+	Use:   "encrypt [file]",
+	Short: "Encrypt a file, or data on stdin, to one or more recipients",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recipients, err := decodeRecipients(encryptRecipients)
+		if err != nil {
+			return err
+		}
+		in, err := openInput(args)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out := openOutput(encryptOutput)
+		defer out.Close()
+
+		writer, err := stream.Encrypt(out, recipients)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(writer, in)
+		if err != nil {
+			return err
+		}
+		return writer.Close()
+	},
+}
+
+func init() {
+	options := encryptCommand.Flags()
+	options.SortFlags = false
+	options.StringArrayVarP(&encryptRecipients, "recipient", "r", nil, "Recipient public key, private key, or certificate file")
+	options.VarP(encryptOutput, "output", "o", "Output file (default stdout)")
+
+	_ = encryptCommand.MarkFlagRequired("recipient")
+}
+
+func decodeRecipients(paths []string) ([]crypto.PublicKey, error) {
+	recipients := make([]crypto.PublicKey, len(paths))
+	for i, path := range paths {
+		chain, err := decodePEMFile(path)
+		if err != nil {
+			return nil, err
+		}
+		recipients[i], err = crypt.PublicKey(chain[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return recipients, nil
+}
+
+func decodePEMFile(path string) (encoding.PEMChain, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return encoding.DecodePEM(file)
+}
+
+// openInput opens the file argument, or stdin. Closing the result leaves
+// stdin open.
+func openInput(args []string) (io.ReadCloser, error) {
+	if len(args) == 0 || args[0] == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(args[0])
+}
+
+// openOutput returns the file opened from output, or stdout. Closing the
+// result leaves stdout open.
+func openOutput(output *flags.File) io.WriteCloser {
+	if output.File() == nil {
+		return nopWriteCloser{os.Stdout}
+	}
+	return output.File()
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxScanFileSize = 1 << 20
+
+var (
+	expiryWithin flags.Time
+	expiryFormat string
+	expiryAll    bool
+)
+
+type expiryReport struct {
+	Path     string    `json:"path"`
+	Subject  string    `json:"subject"`
+	Names    []string  `json:"names"`
+	Serial   string    `json:"serial"`
+	NotAfter time.Time `json:"notAfter"`
+	Days     int       `json:"daysRemaining"`
+	Expiring bool      `json:"expiring"`
+}
+
+var expiryCommand = &cobra.Command{
+	Use:   "expiry [path...]",
+	Short: "Find certificates in files and directories that expire soon",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		now := time.Now()
+		within := now.Add(30 * 24 * time.Hour)
+		if expiryWithin > 0 {
+			within = time.Unix(int64(expiryWithin), 0)
+		}
+
+		var reports []*expiryReport
+		for _, root := range args {
+			err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return expiryWarning(path, err.Error())
+				}
+				if info.IsDir() {
+					if path != root && strings.HasPrefix(info.Name(), ".") {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if info.Mode()&os.ModeSymlink != 0 {
+					info, err = os.Stat(path)
+					if err != nil {
+						return expiryWarning(path, err.Error())
+					}
+				}
+				if !info.Mode().IsRegular() {
+					return expiryWarning(path, "skipped, not a regular file")
+				}
+				if info.Size() > maxScanFileSize {
+					return expiryWarning(path, "skipped, larger than 1 MiB")
+				}
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					return expiryWarning(path, err.Error())
+				}
+				for _, cert := range encoding.ExtractCertificates(data) {
+					reports = append(reports, newExpiryReport(path, cert, now, within))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		sort.SliceStable(reports, func(i, j int) bool {
+			return reports[i].NotAfter.Before(reports[j].NotAfter)
+		})
+		expiring := 0
+		var shown []*expiryReport
+		for _, report := range reports {
+			if report.Expiring {
+				expiring++
+			}
+			if report.Expiring || expiryAll {
+				shown = append(shown, report)
+			}
+		}
+
+		err := writeExpiryReports(shown)
+		if err != nil {
+			return err
+		}
+		if expiring > 0 {
+			return fmt.Errorf("%d certificates expire before %s", expiring, within.UTC().Format(time.RFC3339))
+		}
+		return nil
+	},
+}
+
+func init() {
+	options := expiryCommand.Flags()
+	options.SortFlags = false
+	options.VarP(&expiryWithin, "within", "w", "Report certificates expiring before (default \"720h\")")
+	options.StringVarP(&expiryFormat, "format", "f", "text", "Output format: text, json, or csv")
+	options.BoolVarP(&expiryAll, "all", "a", false, "Report every certificate found")
+}
+
+// expiryWarning reports a path that could not be scanned, and carries on.
+func expiryWarning(path string, message string) error {
+	_, err := fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", path, message)
+	return err
+}
+
+func newExpiryReport(path string, cert *x509.Certificate, now time.Time, within time.Time) *expiryReport {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return &expiryReport{
+		Path:     path,
+		Subject:  cert.Subject.String(),
+		Names:    names,
+		Serial:   cert.SerialNumber.Text(16),
+		NotAfter: cert.NotAfter.UTC(),
+		Days:     int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24)),
+		Expiring: cert.NotAfter.Before(within),
+	}
+}
+
+func writeExpiryReports(reports []*expiryReport) error {
+	switch expiryFormat {
+	case "json":
+		if reports == nil {
+			reports = []*expiryReport{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	case "csv":
+		writer := csv.NewWriter(os.Stdout)
+		_ = writer.Write([]string{"path", "subject", "names", "serial", "not_after", "days_remaining", "expiring"})
+		for _, report := range reports {
+			_ = writer.Write([]string{
+				report.Path,
+				report.Subject,
+				strings.Join(report.Names, " "),
+				report.Serial,
+				report.NotAfter.Format(time.RFC3339),
+				strconv.Itoa(report.Days),
+				strconv.FormatBool(report.Expiring),
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+	case "text":
+		for _, report := range reports {
+			_, err := fmt.Fprintf(os.Stdout, "%5dd  %s  %s  %s  %s\n", report.Days,
+				report.NotAfter.Format("2006-01-02"), report.Path, report.Subject, strings.Join(report.Names, ","))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported format: %s", expiryFormat)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+var (
+	hashAlg    string
+	hashFormat string
+	hashCheck  = flags.FileRead()
+)
+
+var hashCommand = &cobra.Command{
+	Use:   "hash [file...]",
+	Short: "Output the digest of files, or data on stdin",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if hashCheck.File() != nil {
+			return checkDigests(hashCheck.File())
+		}
+		if len(args) == 0 {
+			args = []string{"-"}
+		}
+		for _, path := range args {
+			digest, err := digestFile(path)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(os.Stdout, "%s  %s\n", digest, path)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	options := hashCommand.Flags()
+	options.SortFlags = false
+	options.StringVarP(&hashAlg, "alg", "a", "sha256", "Hash: "+strings.Join(crypt.HashNames(), ", "))
+	options.StringVarP(&hashFormat, "format", "f", "hex", "Digest format: hex, base64, or base64url")
+	options.VarP(hashCheck, "check", "c", "Verify digests listed in a checksum file")
+}
+
+func digestFile(path string) (string, error) {
+	h, err := crypt.NewHash(hashAlg)
+	if err != nil {
+		return "", err
+	}
+	in, err := openInput([]string{path})
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	digest, err := crypt.DigestReader(h, in)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := encodeDigest(digest)
+	return string(encoded), err
+}
+
+func encodeDigest(digest []byte) ([]byte, error) {
+	if hashFormat == "binary" {
+		return nil, errors.New("binary digests are not supported")
+	}
+	return encoding.EncodeBytes(hashFormat, digest)
+}
+
+func checkDigests(checkFile *os.File) error {
+	defer checkFile.Close()
+	failures := 0
+	scanner := bufio.NewScanner(checkFile)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		expected, path, err := parseChecksumLine(line)
+		if err != nil {
+			return err
+		}
+		actual, err := digestFile(path)
+		status := "OK"
+		switch {
+		case err != nil:
+			status = "FAILED open or read"
+			failures++
+		case !digestsEqual(expected, actual):
+			status = "FAILED"
+			failures++
+		}
+		_, err = fmt.Fprintf(os.Stdout, "%s: %s\n", path, status)
+		if err != nil {
+			return err
+		}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return err
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d computed checksums did not match", failures)
+	}
+	return nil
+}
+
+func parseChecksumLine(line string) (string, string, error) {
+	fields := strings.SplitN(line, " ", 2)
+	if len(fields) != 2 {
+		return "", "", fmt.Errorf("invalid checksum line: %s", line)
+	}
+	path := strings.TrimPrefix(strings.TrimPrefix(fields[1], " "), "*")
+	return fields[0], path, nil
+}
+
+func digestsEqual(expected string, actual string) bool {
+	if hashFormat == "hex" {
+		return strings.EqualFold(expected, actual)
+	}
+	return expected == actual
+}
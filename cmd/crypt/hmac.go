@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/spf13/cobra"
+	"os"
+	"reflect"
+)
+
+var (
+	hmacKey    = flags.FileRead()
+	hmacKid    string
+	hmacRaw    bool
+	hmacAlg    string
+	hmacFormat string
+	hmacVerify string
+)
+
+var rawKeyEncodings = encoding.Encodings{
+	encoding.Raw,
+}
+
+var hmacCommand = &cobra.Command{
+	Use:   "hmac [file]",
+	Short: "Output the HMAC of a file, or data on stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := decodeSymmetricKey(hmacKey.File(), hmacKid, hmacRaw)
+		if err != nil {
+			return err
+		}
+		h, err := crypt.NewHMAC(hmacAlg, key)
+		if err != nil {
+			return err
+		}
+		in, err := openInput(args)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		mac, err := crypt.DigestReader(h, in)
+		if err != nil {
+			return err
+		}
+		if hmacVerify != "" {
+			expected, err := encoding.DecodeBytes(hmacFormat, []byte(hmacVerify))
+			if err != nil {
+				return err
+			}
+			if !hmac.Equal(expected, mac) {
+				return errors.New("hmac mismatch")
+			}
+			_, err = fmt.Fprintln(os.Stderr, "HMAC verified")
+			return err
+		}
+		encoded, err := encoding.EncodeBytes(hmacFormat, mac)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, string(encoded))
+		return err
+	},
+}
+
+func init() {
+	options := hmacCommand.Flags()
+	options.SortFlags = false
+	options.VarP(hmacKey, "key", "k", "Key file: base64, base64url, oct JWK, or JWK set")
+	options.StringVar(&hmacKid, "kid", "", "Key ID")
+	options.BoolVar(&hmacRaw, "raw", false, "Treat the key as raw bytes")
+	options.StringVarP(&hmacAlg, "alg", "a", "sha256", "Hash")
+	options.StringVarP(&hmacFormat, "format", "f", "hex", "HMAC format: hex, base64, or base64url")
+	options.StringVar(&hmacVerify, "verify", "", "Expected HMAC to verify against")
+
+	_ = hmacCommand.MarkFlagRequired("key")
+}
+
+func decodeSymmetricKey(keyFile *os.File, kid string, raw bool) ([]byte, error) {
+	defer keyFile.Close()
+	encodings := jcrypt.KeyEncodings
+	if raw {
+		encodings = rawKeyEncodings
+	}
+	decoded, err := encodings.Decode(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	jwk, err := jcrypt.SelectKey(decoded, kid)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := jwk.Key.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("expected a symmetric key, got %v", reflect.TypeOf(jwk.Key))
+	}
+	return key, nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/spf13/cobra"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digitalSignature"},
+	{x509.KeyUsageContentCommitment, "contentCommitment"},
+	{x509.KeyUsageKeyEncipherment, "keyEncipherment"},
+	{x509.KeyUsageDataEncipherment, "dataEncipherment"},
+	{x509.KeyUsageKeyAgreement, "keyAgreement"},
+	{x509.KeyUsageCertSign, "keyCertSign"},
+	{x509.KeyUsageCRLSign, "cRLSign"},
+	{x509.KeyUsageEncipherOnly, "encipherOnly"},
+	{x509.KeyUsageDecipherOnly, "decipherOnly"},
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "any",
+	x509.ExtKeyUsageServerAuth:      "serverAuth",
+	x509.ExtKeyUsageClientAuth:      "clientAuth",
+	x509.ExtKeyUsageCodeSigning:     "codeSigning",
+	x509.ExtKeyUsageEmailProtection: "emailProtection",
+	x509.ExtKeyUsageTimeStamping:    "timeStamping",
+	x509.ExtKeyUsageOCSPSigning:     "ocspSigning",
+}
+
+var inspectCommand = &cobra.Command{
+	Use:   "inspect [file]",
+	Short: "Display certificates and CSRs in a file, or on stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		in, err := openInput(args)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		chain, err := encoding.DecodePEM(in)
+		if err != nil {
+			return err
+		}
+
+		w := &fieldWriter{out: os.Stdout}
+		for i, item := range chain {
+			if i > 0 {
+				w.line("")
+			}
+			switch item.(type) {
+			case *x509.Certificate:
+				inspectCertificate(w, item.(*x509.Certificate))
+			case *x509.CertificateRequest:
+				inspectCertificateRequest(w, item.(*x509.CertificateRequest))
+			default:
+				return fmt.Errorf("unsupported type: %v", reflect.TypeOf(item))
+			}
+		}
+		return w.err
+	},
+}
+
+func inspectCertificate(w *fieldWriter, cert *x509.Certificate) {
+	w.line("Certificate:")
+	w.field("Subject", cert.Subject.String())
+	w.field("Issuer", cert.Issuer.String())
+	w.field("Serial Number", cert.SerialNumber.Text(16))
+	w.field("Not Before", cert.NotBefore.UTC().Format(time.RFC3339))
+	w.field("Not After", cert.NotAfter.UTC().Format(time.RFC3339))
+	w.field("Public Key", describePublicKey(cert.PublicKey))
+	w.field("Signature Algorithm", cert.SignatureAlgorithm.String())
+	if cert.BasicConstraintsValid {
+		w.field("Basic Constraints", describeBasicConstraints(cert))
+	}
+	w.list("Key Usage", keyUsages(cert.KeyUsage))
+	w.list("Extended Key Usage", extKeyUsages(cert))
+	w.field("Subject Key ID", hex.EncodeToString(cert.SubjectKeyId))
+	w.field("Authority Key ID", hex.EncodeToString(cert.AuthorityKeyId))
+	inspectNames(w, cert.DNSNames, cert.IPAddresses, cert.EmailAddresses, uriStrings(cert))
+
+	if hasNameConstraints(cert) {
+		critical := ""
+		if cert.PermittedDNSDomainsCritical {
+			critical = " (critical)"
+		}
+		w.line("  Name Constraints" + critical + ":")
+		w.list("  Permitted DNS", cert.PermittedDNSDomains)
+		w.list("  Excluded DNS", cert.ExcludedDNSDomains)
+		w.list("  Permitted IP", ipNetStrings(cert.PermittedIPRanges))
+		w.list("  Excluded IP", ipNetStrings(cert.ExcludedIPRanges))
+		w.list("  Permitted Email", cert.PermittedEmailAddresses)
+		w.list("  Excluded Email", cert.ExcludedEmailAddresses)
+		w.list("  Permitted URI", cert.PermittedURIDomains)
+		w.list("  Excluded URI", cert.ExcludedURIDomains)
+	}
+	policies := make([]string, len(cert.PolicyIdentifiers))
+	for i, policy := range cert.PolicyIdentifiers {
+		policies[i] = policy.String()
+	}
+	w.list("Certificate Policies", policies)
+	w.list("CRL Distribution Points", cert.CRLDistributionPoints)
+	w.list("OCSP", cert.OCSPServer)
+	w.list("CA Issuers", cert.IssuingCertificateURL)
+	if crypt.HasMustStaple(cert) {
+		w.field("TLS Feature", "status_request (must-staple)")
+	}
+}
+
+func inspectCertificateRequest(w *fieldWriter, csr *x509.CertificateRequest) {
+	w.line("Certificate Request:")
+	w.field("Subject", csr.Subject.String())
+	w.field("Public Key", describePublicKey(csr.PublicKey))
+	w.field("Signature Algorithm", csr.SignatureAlgorithm.String())
+	uris := make([]string, len(csr.URIs))
+	for i, uri := range csr.URIs {
+		uris[i] = uri.String()
+	}
+	inspectNames(w, csr.DNSNames, csr.IPAddresses, csr.EmailAddresses, uris)
+	extensions := make([]string, len(csr.Extensions))
+	for i, extension := range csr.Extensions {
+		extensions[i] = extension.Id.String()
+		if extension.Critical {
+			extensions[i] += " (critical)"
+		}
+	}
+	w.list("Requested Extensions", extensions)
+}
+
+func inspectNames(w *fieldWriter, dnsNames []string, ips []net.IP, emails []string, uris []string) {
+	ipStrings := make([]string, len(ips))
+	for i, ip := range ips {
+		ipStrings[i] = ip.String()
+	}
+	w.list("DNS Names", dnsNames)
+	w.list("IP Addresses", ipStrings)
+	w.list("Email Addresses", emails)
+	w.list("URIs", uris)
+}
+
+func describePublicKey(key interface{}) string {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.(*rsa.PublicKey).N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.(*ecdsa.PublicKey).Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return reflect.TypeOf(key).String()
+	}
+}
+
+func describeBasicConstraints(cert *x509.Certificate) string {
+	if !cert.IsCA {
+		return "CA:FALSE"
+	}
+	if cert.MaxPathLen > 0 || cert.MaxPathLenZero {
+		return fmt.Sprintf("CA:TRUE, pathlen:%d", cert.MaxPathLen)
+	}
+	return "CA:TRUE"
+}
+
+func keyUsages(usage x509.KeyUsage) []string {
+	var names []string
+	for _, keyUsage := range keyUsageNames {
+		if usage&keyUsage.usage != 0 {
+			names = append(names, keyUsage.name)
+		}
+	}
+	return names
+}
+
+func extKeyUsages(cert *x509.Certificate) []string {
+	var names []string
+	for _, usage := range cert.ExtKeyUsage {
+		name, ok := extKeyUsageNames[usage]
+		if !ok {
+			name = fmt.Sprintf("%d", usage)
+		}
+		names = append(names, name)
+	}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		names = append(names, oid.String())
+	}
+	return names
+}
+
+func hasNameConstraints(cert *x509.Certificate) bool {
+	return len(cert.PermittedDNSDomains)+len(cert.ExcludedDNSDomains)+
+		len(cert.PermittedIPRanges)+len(cert.ExcludedIPRanges)+
+		len(cert.PermittedEmailAddresses)+len(cert.ExcludedEmailAddresses)+
+		len(cert.PermittedURIDomains)+len(cert.ExcludedURIDomains) > 0
+}
+
+func ipNetStrings(ranges []*net.IPNet) []string {
+	values := make([]string, len(ranges))
+	for i, ipRange := range ranges {
+		values[i] = ipRange.String()
+	}
+	return values
+}
+
+func uriStrings(cert *x509.Certificate) []string {
+	values := make([]string, len(cert.URIs))
+	for i, uri := range cert.URIs {
+		values[i] = uri.String()
+	}
+	return values
+}
+
+type fieldWriter struct {
+	out io.Writer
+	err error
+}
+
+func (w *fieldWriter) line(text string) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintln(w.out, text)
+	}
+}
+
+func (w *fieldWriter) field(name string, value string) {
+	if value != "" {
+		w.line(fmt.Sprintf("  %s: %s", name, value))
+	}
+}
+
+func (w *fieldWriter) list(name string, values []string) {
+	w.field(name, strings.Join(values, ", "))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/spf13/cobra"
+	"github.com/square/go-jose/v3"
+	"io/ioutil"
+	"os"
+)
+
+var (
+	kdfAlg          string
+	kdfSecret       = flags.FileRead()
+	kdfSecretFormat string
+	kdfSalt         string
+	kdfSaltFormat   string
+	kdfInfo         string
+	kdfHash         string
+	kdfLength       int
+	kdfIterations   int
+	kdfScrypt       = crypt.DefaultScryptParams
+	kdfArgon2       = crypt.DefaultArgon2Params
+	kdfFormat       string
+	kdfKid          string
+)
+
+var kdfCommand = &cobra.Command{
+	Use:   "kdf",
+	Short: "Derive a key from a secret, or password, on stdin",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		secret, err := readKDFSecret()
+		if err != nil {
+			return err
+		}
+		salt, err := decodeKDFSalt()
+		if err != nil {
+			return err
+		}
+		key, err := deriveKey(secret, salt)
+		if err != nil {
+			return err
+		}
+		return writeDerivedKey(key)
+	},
+}
+
+func init() {
+	options := kdfCommand.Flags()
+	options.SortFlags = false
+	options.StringVarP(&kdfAlg, "alg", "a", "hkdf", "KDF: hkdf, hkdf-extract, hkdf-expand, pbkdf2, scrypt, or argon2id")
+	options.VarP(kdfSecret, "secret", "s", "Secret file (default stdin, less one trailing newline)")
+	options.StringVar(&kdfSecretFormat, "secret-format", "binary", "Secret format: binary, base64, base64url, or hex")
+	options.StringVar(&kdfSalt, "salt", "", "Salt")
+	options.StringVar(&kdfSaltFormat, "salt-format", "text", "Salt format: text, base64, base64url, or hex")
+	options.StringVar(&kdfInfo, "info", "", "HKDF info")
+	options.StringVar(&kdfHash, "hash", "sha256", "HKDF and PBKDF2 hash")
+	options.IntVarP(&kdfLength, "length", "l", 32, "Derived key length in bytes")
+	options.IntVar(&kdfIterations, "iterations", 600000, "PBKDF2 iterations")
+	options.IntVar(&kdfScrypt.N, "scrypt-n", kdfScrypt.N, "scrypt CPU/memory cost")
+	options.IntVar(&kdfScrypt.R, "scrypt-r", kdfScrypt.R, "scrypt block size")
+	options.IntVar(&kdfScrypt.P, "scrypt-p", kdfScrypt.P, "scrypt parallelism")
+	options.Uint32Var(&kdfArgon2.Time, "argon2-time", kdfArgon2.Time, "Argon2id passes")
+	options.Uint32Var(&kdfArgon2.Memory, "argon2-memory", kdfArgon2.Memory, "Argon2id memory in KiB")
+	options.Uint8Var(&kdfArgon2.Threads, "argon2-threads", kdfArgon2.Threads, "Argon2id parallelism")
+	options.StringVarP(&kdfFormat, "format", "f", "base64", "Output format: base64, base64url, hex, binary, or jwk")
+	options.StringVar(&kdfKid, "kid", "", "Key ID of the JWK")
+}
+
+// readKDFSecret reads the secret file, or stdin without one trailing newline,
+// so that echo secret | crypt kdf derives from "secret".
+func readKDFSecret() ([]byte, error) {
+	if kdfSecret.File() != nil {
+		defer kdfSecret.File().Close()
+		data, err := ioutil.ReadAll(kdfSecret.File())
+		if err != nil {
+			return nil, err
+		}
+		return encoding.DecodeBytes(kdfSecretFormat, data)
+	}
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasSuffix(data, []byte("\n")) {
+		data = bytes.TrimSuffix(bytes.TrimSuffix(data, []byte("\n")), []byte("\r"))
+	}
+	return encoding.DecodeBytes(kdfSecretFormat, data)
+}
+
+func decodeKDFSalt() ([]byte, error) {
+	if kdfSaltFormat == "text" {
+		return []byte(kdfSalt), nil
+	}
+	return encoding.DecodeBytes(kdfSaltFormat, []byte(kdfSalt))
+}
+
+func deriveKey(secret []byte, salt []byte) ([]byte, error) {
+	switch kdfAlg {
+	case "hkdf":
+		return crypt.HKDF(kdfHash, secret, salt, []byte(kdfInfo), kdfLength)
+	case "hkdf-extract":
+		return crypt.HKDFExtract(kdfHash, secret, salt)
+	case "hkdf-expand":
+		return crypt.HKDFExpand(kdfHash, secret, []byte(kdfInfo), kdfLength)
+	case "pbkdf2":
+		return crypt.PBKDF2(kdfHash, secret, salt, kdfIterations, kdfLength)
+	case "scrypt":
+		return crypt.Scrypt(secret, salt, kdfScrypt, kdfLength)
+	case "argon2id":
+		return crypt.Argon2id(secret, salt, kdfArgon2, kdfLength)
+	default:
+		return nil, fmt.Errorf("unsupported kdf: %s", kdfAlg)
+	}
+}
+
+func writeDerivedKey(key []byte) error {
+	if kdfFormat == "jwk" {
+		return json.NewEncoder(os.Stdout).Encode(&jose.JSONWebKey{Key: key, KeyID: kdfKid})
+	}
+	encoded, err := encoding.EncodeBytes(kdfFormat, key)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(encoded)
+	if err != nil || kdfFormat == "binary" || kdfFormat == "raw" {
+		return err
+	}
+	_, err = fmt.Fprintln(os.Stdout)
+	return err
+}
@@ -1,9 +1,54 @@
 package main
 
 import (
-	"github.com/credding/crypt/pkg/crypt"
+	"github.com/spf13/cobra"
+	"os"
 )
 
+var rootCommand = &cobra.Command{
+	Use:   "crypt",
+	Short: "Simple cryptography toolset",
+}
+
 func main() {
-	crypt.Execute()
+	err := rootCommand.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	cobra.EnableCommandSorting = false
+	rootCommand.AddCommand(
+		rsaCommand,
+		ecdsaCommand,
+		ed25519Command,
+		x25519Command,
+		csrCommand,
+		certCommand,
+		renewCommand,
+		crossSignCommand,
+		inspectCommand,
+		devCertCommand,
+		bundleCommand,
+		expiryCommand,
+		tlsCommand,
+		acmeCommand,
+		spiffeCommand,
+		publicCommand,
+		convertCommand,
+		encryptCommand,
+		decryptCommand,
+		signCommand,
+		verifyCommand,
+		cmsCommand,
+		hashCommand,
+		hmacCommand,
+		kdfCommand,
+		passwordCommand,
+		splitCommand,
+		combineCommand,
+		matchCommand,
+		randCommand,
+	)
 }
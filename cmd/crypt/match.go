@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/spf13/cobra"
+	"github.com/square/go-jose/v3"
+	"io/ioutil"
+	"os"
+	"reflect"
+)
+
+type matchItem struct {
+	name string
+	key  interface{}
+}
+
+var matchCommand = &cobra.Command{
+	Use:   "match file...",
+	Short: "Check that keys, certificates, CSRs and JWKs share the same public key",
+	Args:  cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var items []matchItem
+		for _, path := range args {
+			decoded, err := decodeMatchItems(path)
+			if err != nil {
+				return err
+			}
+			items = append(items, decoded...)
+		}
+
+		var hashes []string
+		groups := make(map[string][]matchItem)
+		for _, item := range items {
+			hash, err := crypt.SPKIHash(item.key)
+			if err != nil {
+				return fmt.Errorf("%s: %w", item.name, err)
+			}
+			spki := hex.EncodeToString(hash)
+			if _, ok := groups[spki]; !ok {
+				hashes = append(hashes, spki)
+			}
+			groups[spki] = append(groups[spki], item)
+		}
+
+		for _, spki := range hashes {
+			_, err := fmt.Fprintf(os.Stdout, "sha256:%s\n", spki)
+			if err != nil {
+				return err
+			}
+			for _, item := range groups[spki] {
+				_, err = fmt.Fprintf(os.Stdout, "  %s (%s)\n", item.name, describeMatchItem(item.key))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if len(groups) > 1 {
+			return fmt.Errorf("found %d different public keys", len(groups))
+		}
+		return nil
+	},
+}
+
+func decodeMatchItems(path string) ([]matchItem, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := jcrypt.KeyEncodings.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch decoded.(type) {
+	case encoding.PEMChain:
+		chain := decoded.(encoding.PEMChain)
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("%s: no keys found", path)
+		}
+		if len(chain) == 1 {
+			return []matchItem{{name: path, key: chain[0]}}, nil
+		}
+		items := make([]matchItem, len(chain))
+		for i, key := range chain {
+			items[i] = matchItem{name: fmt.Sprintf("%s#%d", path, i), key: key}
+		}
+		return items, nil
+	case *jose.JSONWebKeySet:
+		keys := decoded.(*jose.JSONWebKeySet).Keys
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("%s: no keys found", path)
+		}
+		items := make([]matchItem, len(keys))
+		for i, key := range keys {
+			name := fmt.Sprintf("%s#%d", path, i)
+			if key.KeyID != "" {
+				name = fmt.Sprintf("%s#%s", path, key.KeyID)
+			}
+			items[i] = matchItem{name: name, key: key.Key}
+		}
+		return items, nil
+	case *jose.JSONWebKey:
+		return []matchItem{{name: path, key: decoded.(*jose.JSONWebKey).Key}}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type: %v", path, reflect.TypeOf(decoded))
+	}
+}
+
+func describeMatchItem(key interface{}) string {
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, *ecdh.PrivateKey, ed25519.PrivateKey:
+		return "private key"
+	case *x509.Certificate:
+		return "certificate " + key.(*x509.Certificate).Subject.String()
+	case *x509.CertificateRequest:
+		return "certificate request " + key.(*x509.CertificateRequest).Subject.String()
+	default:
+		return "public key"
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/credding/crypt/pkg/prompt"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+var (
+	passwordFile   = flags.FileRead()
+	passwordEnv    string
+	passwordParams = crypt.DefaultPasswordParams
+
+	passwordHashAlg string
+
+	passwordVerifyHash   = flags.FileRead()
+	passwordVerifyStrict bool
+)
+
+var passwordCommand = &cobra.Command{
+	Use:   "password",
+	Short: "Hash and verify passwords",
+}
+
+var passwordHashCommand = &cobra.Command{
+	Use:   "hash",
+	Short: "Output a PHC format hash of a password",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		password, err := prompt.Password(passwordFile.File(), passwordEnv, true)
+		if err != nil {
+			return err
+		}
+		hash, err := crypt.HashPassword(passwordHashAlg, password, passwordParams)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, hash)
+		return err
+	},
+}
+
+var passwordVerifyCommand = &cobra.Command{
+	Use:   "verify [hash]",
+	Short: "Verify a password against a stored hash, and check it against policy",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hash, err := readStoredHash(args)
+		if err != nil {
+			return err
+		}
+		violations, err := crypt.PasswordPolicyViolations(hash, passwordParams)
+		if err != nil {
+			return err
+		}
+		password, err := prompt.Password(passwordFile.File(), passwordEnv, false)
+		if err != nil {
+			return err
+		}
+		err = crypt.VerifyPassword(hash, password)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stderr, "Password verified")
+		if err != nil {
+			return err
+		}
+
+		for _, violation := range violations {
+			_, err = fmt.Fprintf(os.Stderr, "Warning: %s\n", violation)
+			if err != nil {
+				return err
+			}
+		}
+		if passwordVerifyStrict && len(violations) > 0 {
+			return errors.New("hash parameters are below policy")
+		}
+		return nil
+	},
+}
+
+func init() {
+	passwordCommand.AddCommand(passwordHashCommand, passwordVerifyCommand)
+
+	options := passwordCommand.PersistentFlags()
+	options.SortFlags = false
+	options.Var(passwordFile, "password-file", "Read the password from a file")
+	options.StringVar(&passwordEnv, "password-env", "", "Read the password from an environment variable")
+	options.Uint32Var(&passwordParams.Argon2.Memory, "argon2-memory", passwordParams.Argon2.Memory, "Argon2id memory in KiB")
+	options.Uint32Var(&passwordParams.Argon2.Time, "argon2-time", passwordParams.Argon2.Time, "Argon2id passes")
+	options.Uint8Var(&passwordParams.Argon2.Threads, "argon2-threads", passwordParams.Argon2.Threads, "Argon2id parallelism")
+	options.IntVar(&passwordParams.Scrypt.N, "scrypt-n", passwordParams.Scrypt.N, "scrypt CPU/memory cost, a power of two")
+	options.IntVar(&passwordParams.Scrypt.R, "scrypt-r", passwordParams.Scrypt.R, "scrypt block size")
+	options.IntVar(&passwordParams.Scrypt.P, "scrypt-p", passwordParams.Scrypt.P, "scrypt parallelism")
+	options.IntVar(&passwordParams.BcryptCost, "bcrypt-cost", passwordParams.BcryptCost, "bcrypt cost")
+	options.IntVar(&passwordParams.PBKDF2Iterations, "pbkdf2-iterations", passwordParams.PBKDF2Iterations, "PBKDF2-SHA256 iterations")
+
+	hashOptions := passwordHashCommand.Flags()
+	hashOptions.SortFlags = false
+	hashOptions.StringVarP(&passwordHashAlg, "alg", "a", "argon2id", "Hash: "+strings.Join(crypt.PasswordHashes, ", "))
+
+	verifyOptions := passwordVerifyCommand.Flags()
+	verifyOptions.SortFlags = false
+	verifyOptions.Var(passwordVerifyHash, "hash-file", "Read the stored hash from a file")
+	verifyOptions.BoolVar(&passwordVerifyStrict, "strict", false, "Fail if the hash parameters are below policy")
+}
+
+func readStoredHash(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	if passwordVerifyHash.File() == nil {
+		return "", errors.New("stored hash not provided")
+	}
+	defer passwordVerifyHash.File().Close()
+	data, err := ioutil.ReadAll(passwordVerifyHash.File())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var publicCommand = &cobra.Command{
+	Use:   "public",
+	Short: "Output the public key given a private key, or certificate on stdin",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := encoding.DecodePEM(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		chain, err := crypt.PublicChain(key)
+		if err != nil {
+			return err
+		}
+		return encoding.EncodePEM(os.Stdout, chain)
+	},
+}
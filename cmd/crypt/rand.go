@@ -1,9 +1,9 @@
-package crypt
+package main
 
 import (
 	"encoding/base64"
+	"github.com/credding/crypt/pkg/crypt"
 	"github.com/spf13/cobra"
-	"math/rand"
 	"os"
 	"strconv"
 )
@@ -18,8 +18,7 @@ var randCommand = &cobra.Command{
 			return err
 		}
 
-		data := make([]byte, bytes)
-		_, err = rand.Read(data)
+		data, err := crypt.RandomBytes(bytes)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/spf13/cobra"
+	"os"
+	"reflect"
+	"time"
+)
+
+var (
+	renewParent     = flags.FileRead()
+	renewSigningKey = flags.FileRead()
+	renewSubjectKey = flags.FileRead()
+	renewExpiry     flags.Time
+	renewCSR        bool
+)
+
+var renewCommand = &cobra.Command{
+	Use:   "renew",
+	Short: "Renew a certificate, or create a renewal CSR, given a certificate on stdin",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		certPem, err := encoding.DecodePEM(os.Stdin)
+		if err != nil {
+			return err
+		}
+		cert, ok := certPem[0].(*x509.Certificate)
+		if !ok {
+			return fmt.Errorf("expected a certificate, got %v", reflect.TypeOf(certPem[0]))
+		}
+
+		if renewCSR {
+			if renewSubjectKey.File() == nil {
+				return errors.New("a renewal CSR requires --subject-key")
+			}
+			key, err := decodeSigningKey(renewSubjectKey.File())
+			if err != nil {
+				return err
+			}
+			csr, err := crypt.CreateRenewalRequest(cert, key)
+			if err != nil {
+				return err
+			}
+			return encoding.EncodePEM(os.Stdout, csr)
+		}
+
+		if renewSigningKey.File() == nil {
+			return errors.New("renewing a certificate requires --key, unless --csr generates a renewal request")
+		}
+		publicKey, err := renewalPublicKey()
+		if err != nil {
+			return err
+		}
+		issuer, err := renewalIssuer()
+		if err != nil {
+			return err
+		}
+		renewed, err := crypt.RenewCertificate(cert, publicKey, issuer, &crypt.CertificateOptions{
+			NotAfter: renewalExpiry(),
+		})
+		if err != nil {
+			return err
+		}
+		return encoding.EncodePEM(os.Stdout, renewed)
+	},
+}
+
+func init() {
+	options := renewCommand.Flags()
+	options.SortFlags = false
+	options.VarP(renewParent, "parent", "p", "Parent certificate (default self-signed)")
+	options.VarP(renewSigningKey, "key", "k", "Certificate signing key")
+	options.VarP(renewSubjectKey, "subject-key", "s", "New subject key (default the certificate's key)")
+	options.VarP(&renewExpiry, "expires", "e", "Certificate expiry (default the certificate's validity period)")
+	options.BoolVar(&renewCSR, "csr", false, "Generate a renewal CSR signed by the subject key")
+}
+
+func renewalPublicKey() (crypto.PublicKey, error) {
+	if renewSubjectKey.File() == nil {
+		return nil, nil
+	}
+	defer renewSubjectKey.File().Close()
+	keyPem, err := encoding.DecodePEM(renewSubjectKey.File())
+	if err != nil {
+		return nil, err
+	}
+	return crypt.PublicKey(keyPem[0])
+}
+
+func renewalIssuer() (*crypt.Issuer, error) {
+	key, err := decodeSigningKey(renewSigningKey.File())
+	if err != nil {
+		return nil, err
+	}
+	if renewParent.File() == nil {
+		return &crypt.Issuer{Key: key}, nil
+	}
+	defer renewParent.File().Close()
+	parentPem, err := encoding.DecodePEM(renewParent.File())
+	if err != nil {
+		return nil, err
+	}
+	parent, ok := parentPem[0].(*x509.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("expected parent to be a certificate, got %v", reflect.TypeOf(parentPem[0]))
+	}
+	return &crypt.Issuer{Certificate: parent, Key: key}, nil
+}
+
+func renewalExpiry() time.Time {
+	if renewExpiry > 0 {
+		return time.Unix(int64(renewExpiry), 0)
+	}
+	return time.Time{}
+}
@@ -1,8 +1,7 @@
-package crypt
+package main
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
+	"github.com/credding/crypt/pkg/crypt"
 	"github.com/credding/crypt/pkg/encoding"
 	"github.com/spf13/cobra"
 	"os"
@@ -16,7 +15,7 @@ var rsaCommand = &cobra.Command{
 	Use:   "rsa",
 	Short: "Generate a RSA key",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		key, err := rsa.GenerateKey(rand.Reader, rsaBits)
+		key, err := crypt.GenerateRSAKey(rsaBits)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	signKey      = flags.FileRead()
+	signHash     string
+	signPSS      bool
+	signRawECDSA bool
+	signFormat   string
+	signOutput   = flags.FileWrite()
+)
+
+var signCommand = &cobra.Command{
+	Use:   "sign [file]",
+	Short: "Generate a detached signature of a file, or data on stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := decodeSigningKey(signKey.File())
+		if err != nil {
+			return err
+		}
+		opts, err := signatureOptions(key.Public(), signHash, signPSS, signRawECDSA)
+		if err != nil {
+			return err
+		}
+		in, err := openInput(args)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		signature, err := crypt.SignReader(in, key, opts)
+		if err != nil {
+			return err
+		}
+		encoded, err := encoding.EncodeBytes(signFormat, signature)
+		if err != nil {
+			return err
+		}
+
+		out := openOutput(signOutput)
+		defer out.Close()
+		_, err = out.Write(encoded)
+		if err != nil {
+			return err
+		}
+		if signFormat != "binary" {
+			_, err = out.Write([]byte("\n"))
+		}
+		return err
+	},
+}
+
+func init() {
+	options := signCommand.Flags()
+	options.SortFlags = false
+	options.VarP(signKey, "key", "k", "Signing key")
+	options.StringVar(&signHash, "hash", "", "Hash: sha256, sha384, or sha512 (default sha256, not used for ed25519)")
+	options.BoolVar(&signPSS, "pss", false, "Use RSA-PSS instead of PKCS #1 v1.5")
+	options.BoolVar(&signRawECDSA, "raw-ecdsa", false, "Encode ECDSA signatures as raw r||s instead of DER")
+	options.StringVarP(&signFormat, "format", "f", "base64", "Signature format: base64, hex, or binary")
+	options.VarP(signOutput, "output", "o", "Output file (default stdout)")
+
+	_ = signCommand.MarkFlagRequired("key")
+}
+
+func signatureOptions(key crypto.PublicKey, hashName string, pss bool, rawECDSA bool) (*crypt.SignatureOptions, error) {
+	opts := &crypt.SignatureOptions{PSS: pss, RawECDSA: rawECDSA}
+	if hashName != "" {
+		hash, err := crypt.ParseSignatureHash(hashName)
+		if err != nil {
+			return nil, err
+		}
+		opts.Hash = hash
+	}
+	return opts, nil
+}
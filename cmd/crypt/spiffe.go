@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/credding/crypt/pkg/spiffe"
+	"github.com/spf13/cobra"
+	"github.com/square/go-jose/v3"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	spiffeID     string
+	spiffeParent = flags.FileRead()
+	spiffeKey    = flags.FileRead()
+	spiffeKeyID  string
+	spiffeExpiry flags.Time
+	spiffeChain  bool
+	spiffeAud    []string
+
+	spiffeTrustDomain     string
+	spiffeBundle          = flags.FileRead()
+	spiffeX509Authorities []string
+	spiffeJWTAuthorities  []string
+	spiffeSequence        uint64
+	spiffeRefreshHint     time.Duration
+)
+
+var spiffeCommand = &cobra.Command{
+	Use:   "spiffe",
+	Short: "Issue and validate SPIFFE SVIDs and trust bundles",
+}
+
+var spiffeX509SVIDCommand = &cobra.Command{
+	Use:   "x509-svid",
+	Short: "Issue an X.509-SVID for a CSR or public key on stdin",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := spiffe.ParseID(spiffeID)
+		if err != nil {
+			return err
+		}
+		subjectPem, err := encoding.DecodePEM(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if csr, ok := subjectPem[0].(*x509.CertificateRequest); ok {
+			err = csr.CheckSignature()
+			if err != nil {
+				return fmt.Errorf("invalid CSR signature: %w", err)
+			}
+		}
+		publicKey, err := crypt.PublicKey(subjectPem[0])
+		if err != nil {
+			return err
+		}
+		key, err := decodeSigningKey(spiffeKey.File())
+		if err != nil {
+			return err
+		}
+		defer spiffeParent.File().Close()
+		parents, err := decodeCertificateChain(spiffeParent.File())
+		if err != nil {
+			return err
+		}
+		var notAfter time.Time
+		if spiffeExpiry > 0 {
+			notAfter = time.Unix(int64(spiffeExpiry), 0)
+		}
+		svid, err := spiffe.IssueX509SVID(id, publicKey, &crypt.Issuer{Certificate: parents[0], Key: key}, notAfter)
+		if err != nil {
+			return err
+		}
+		if !spiffeChain {
+			return encoding.EncodePEM(os.Stdout, svid)
+		}
+		var intermediates []*x509.Certificate
+		for _, parent := range parents {
+			if !crypt.IsSelfSigned(parent) {
+				intermediates = append(intermediates, parent)
+			}
+		}
+		return encoding.EncodePEM(os.Stdout, certificateChain(svid, intermediates))
+	},
+}
+
+var spiffeJWTSVIDCommand = &cobra.Command{
+	Use:   "jwt-svid",
+	Short: "Issue a JWT-SVID",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := spiffe.ParseID(spiffeID)
+		if err != nil {
+			return err
+		}
+		defer spiffeKey.File().Close()
+		decoded, err := jcrypt.KeyEncodings.Decode(spiffeKey.File())
+		if err != nil {
+			return err
+		}
+		key, err := jcrypt.SelectKey(decoded, spiffeKeyID)
+		if err != nil {
+			return err
+		}
+		err = setThumbprintKeyID(key)
+		if err != nil {
+			return err
+		}
+		var expiry time.Time
+		if spiffeExpiry > 0 {
+			expiry = time.Unix(int64(spiffeExpiry), 0)
+		}
+		token, err := spiffe.IssueJWTSVID(id, spiffeAud, key, expiry)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, token)
+		return err
+	},
+}
+
+var spiffeBundleCommand = &cobra.Command{
+	Use:   "bundle",
+	Short: "Build a SPIFFE trust bundle, or add authorities to an existing one",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bundle, err := decodeSPIFFEBundle()
+		if err != nil {
+			return err
+		}
+		for _, path := range spiffeX509Authorities {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			certs := encoding.ExtractCertificates(data)
+			if len(certs) == 0 {
+				return fmt.Errorf("%s: no certificates found", path)
+			}
+			bundle.X509Authorities = append(bundle.X509Authorities, certs...)
+		}
+		for _, path := range spiffeJWTAuthorities {
+			keys, err := decodeJWTAuthorities(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			bundle.JWTAuthorities = append(bundle.JWTAuthorities, keys...)
+		}
+		if cmd.Flags().Changed("sequence") {
+			bundle.SequenceNumber = spiffeSequence
+		} else if spiffeBundle.File() != nil {
+			bundle.SequenceNumber++
+		}
+		if cmd.Flags().Changed("refresh-hint") {
+			bundle.RefreshHint = spiffeRefreshHint
+		}
+		data, err := bundle.Marshal()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(os.Stdout, "%s\n", data)
+		return err
+	},
+}
+
+var spiffeValidateCommand = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate an X.509-SVID chain or JWT-SVID on stdin against a trust bundle",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bundle, err := decodeSPIFFEBundle()
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+
+		w := &fieldWriter{out: os.Stdout}
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+			certs := encoding.ExtractCertificates(data)
+			id, chains, err := spiffe.ValidateX509SVID(certs, bundle)
+			if err != nil {
+				return err
+			}
+			w.line("X.509-SVID:")
+			w.field("SPIFFE ID", id.String())
+			w.field("Not After", certs[0].NotAfter.UTC().Format(time.RFC3339))
+			w.field("Authority", chains[0][len(chains[0])-1].Subject.String())
+			return w.err
+		}
+
+		if len(spiffeAud) != 1 {
+			return errors.New("JWT-SVID validation needs exactly one --audience")
+		}
+		svid, err := spiffe.ValidateJWTSVID(strings.TrimSpace(string(data)), bundle, spiffeAud[0])
+		if err != nil {
+			return err
+		}
+		w.line("JWT-SVID:")
+		w.field("SPIFFE ID", svid.ID.String())
+		w.list("Audience", svid.Audience)
+		w.field("Expires", svid.Expiry.UTC().Format(time.RFC3339))
+		return w.err
+	},
+}
+
+func init() {
+	spiffeCommand.AddCommand(
+		spiffeX509SVIDCommand,
+		spiffeJWTSVIDCommand,
+		spiffeBundleCommand,
+		spiffeValidateCommand,
+	)
+
+	options := spiffeX509SVIDCommand.Flags()
+	options.SortFlags = false
+	options.StringVar(&spiffeID, "id", "", "SPIFFE ID, as spiffe://trust-domain/path")
+	options.VarP(spiffeParent, "parent", "p", "Signing CA certificate, and its chain")
+	options.VarP(spiffeKey, "key", "k", "Signing CA key")
+	options.VarP(&spiffeExpiry, "expires", "e", "SVID expiry (default \"1h\")")
+	options.BoolVar(&spiffeChain, "chain", false, "Output the intermediates after the SVID")
+	_ = spiffeX509SVIDCommand.MarkFlagRequired("id")
+	_ = spiffeX509SVIDCommand.MarkFlagRequired("parent")
+	_ = spiffeX509SVIDCommand.MarkFlagRequired("key")
+
+	options = spiffeJWTSVIDCommand.Flags()
+	options.SortFlags = false
+	options.StringVar(&spiffeID, "id", "", "SPIFFE ID, as spiffe://trust-domain/path")
+	options.StringSliceVarP(&spiffeAud, "audience", "a", nil, "Audience")
+	options.VarP(spiffeKey, "key", "k", "Signing key: PEM, JWK, or JWK set")
+	options.StringVar(&spiffeKeyID, "kid", "", "Key ID (default the key's, or its thumbprint)")
+	options.VarP(&spiffeExpiry, "expires", "e", "SVID expiry (default \"5m\")")
+	_ = spiffeJWTSVIDCommand.MarkFlagRequired("id")
+	_ = spiffeJWTSVIDCommand.MarkFlagRequired("audience")
+	_ = spiffeJWTSVIDCommand.MarkFlagRequired("key")
+
+	options = spiffeBundleCommand.Flags()
+	options.SortFlags = false
+	options.StringVarP(&spiffeTrustDomain, "trust-domain", "t", "", "Trust domain")
+	options.VarP(spiffeBundle, "bundle", "b", "Existing trust bundle to add to")
+	options.StringSliceVar(&spiffeX509Authorities, "x509-authority", nil, "File of X.509-SVID root certificates")
+	options.StringSliceVar(&spiffeJWTAuthorities, "jwt-authority", nil, "File of JWT-SVID keys: PEM, JWK, or JWK set")
+	options.Uint64Var(&spiffeSequence, "sequence", 0, "Sequence number (default the existing bundle's plus one)")
+	options.DurationVar(&spiffeRefreshHint, "refresh-hint", 0, "Refresh hint")
+	_ = spiffeBundleCommand.MarkFlagRequired("trust-domain")
+
+	options = spiffeValidateCommand.Flags()
+	options.SortFlags = false
+	options.StringVarP(&spiffeTrustDomain, "trust-domain", "t", "", "Trust domain")
+	options.VarP(spiffeBundle, "bundle", "b", "Trust bundle")
+	options.StringSliceVarP(&spiffeAud, "audience", "a", nil, "Expected JWT-SVID audience")
+	_ = spiffeValidateCommand.MarkFlagRequired("trust-domain")
+	_ = spiffeValidateCommand.MarkFlagRequired("bundle")
+}
+
+func decodeSPIFFEBundle() (*spiffe.Bundle, error) {
+	if spiffeBundle.File() == nil {
+		trustDomain, err := spiffe.ParseTrustDomain(spiffeTrustDomain)
+		if err != nil {
+			return nil, err
+		}
+		return &spiffe.Bundle{TrustDomain: trustDomain}, nil
+	}
+	defer spiffeBundle.File().Close()
+	data, err := ioutil.ReadAll(spiffeBundle.File())
+	if err != nil {
+		return nil, err
+	}
+	return spiffe.ParseBundle(spiffeTrustDomain, data)
+}
+
+func decodeJWTAuthorities(path string) ([]jose.JSONWebKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := jcrypt.DecodeKey(data)
+	if err != nil {
+		return nil, err
+	}
+	var keys []jose.JSONWebKey
+	if jwks, ok := decoded.(*jose.JSONWebKeySet); ok {
+		keys = jwks.Keys
+	} else {
+		key, err := jcrypt.SelectKey(decoded, "")
+		if err != nil {
+			return nil, err
+		}
+		keys = []jose.JSONWebKey{*key}
+	}
+	for i := range keys {
+		err = setThumbprintKeyID(&keys[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// setThumbprintKeyID gives a key without a key ID its RFC 7638 thumbprint,
+// so PEM keys get the same key ID in bundles and JWT-SVIDs.
+func setThumbprintKeyID(key *jose.JSONWebKey) error {
+	if key.KeyID != "" {
+		return nil
+	}
+	if _, ok := key.Key.([]byte); ok {
+		return fmt.Errorf("unsupported JWT-SVID key type: %v", reflect.TypeOf(key.Key))
+	}
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	key.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/pem"
+	"fmt"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/credding/crypt/pkg/shamir"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var (
+	splitShares    int
+	splitThreshold int
+	splitOutput    string
+	splitRaw       bool
+)
+
+var splitCommand = &cobra.Command{
+	Use:   "split [file]",
+	Short: "Split a secret or private key, or data on stdin, into shares",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		secret, err := readInput(args)
+		if err != nil {
+			return err
+		}
+		if !splitRaw {
+			_, err = jcrypt.KeyEncodings.Unmarshal(secret)
+			if err != nil {
+				return fmt.Errorf("%w, use --raw to split other data", err)
+			}
+		}
+
+		shares, err := shamir.Split(secret, splitShares, splitThreshold)
+		if err != nil {
+			return err
+		}
+		if splitOutput == "" {
+			for _, share := range shares {
+				err = pem.Encode(os.Stdout, share.EncodePEM())
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for _, share := range shares {
+			err = writeShare(fmt.Sprintf("%s-%d.pem", splitOutput, share.Index), share)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	options := splitCommand.Flags()
+	options.SortFlags = false
+	options.IntVarP(&splitShares, "shares", "n", 5, "Number of shares")
+	options.IntVarP(&splitThreshold, "threshold", "k", 3, "Number of shares needed to combine")
+	options.StringVarP(&splitOutput, "output", "o", "", "Write each share to <output>-<n>.pem (default stdout)")
+	options.BoolVar(&splitRaw, "raw", false, "Split the input without checking it decodes as a key")
+}
+
+func writeShare(path string, share *shamir.Share) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	err = pem.Encode(file, share.EncodePEM())
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/credding/crypt/pkg/tlsutil"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ocsp"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+var (
+	tlsProbeCert       = flags.FileRead()
+	tlsProbeKey        = flags.FileRead()
+	tlsProbeServerName string
+	tlsProbeALPN       []string
+	tlsProbeTrust      = flags.FileRead()
+	tlsProbeTimeout    time.Duration
+
+	tlsServeListen      string
+	tlsServeCert        = flags.FileRead()
+	tlsServeKey         = flags.FileRead()
+	tlsServeNames       []string
+	tlsServeCAOutput    = flags.FileWrite()
+	tlsServeClientCA    = flags.FileRead()
+	tlsServeRequestCert bool
+	tlsServeALPN        []string
+	tlsServeEcho        bool
+)
+
+var ocspStatuses = map[int]string{
+	ocsp.Good:    "good",
+	ocsp.Revoked: "revoked",
+	ocsp.Unknown: "unknown",
+}
+
+var tlsCommand = &cobra.Command{
+	Use:   "tls",
+	Short: "Probe and serve TLS endpoints",
+}
+
+var tlsProbeCommand = &cobra.Command{
+	Use:   "probe host:port",
+	Short: "Handshake with a TLS server and show what it presents",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := &tlsutil.ProbeOptions{
+			ServerName: tlsProbeServerName,
+			NextProtos: tlsProbeALPN,
+			Timeout:    tlsProbeTimeout,
+		}
+		var err error
+		opts.Certificate, err = decodeTLSCertificate(tlsProbeCert, tlsProbeKey)
+		if err != nil {
+			return err
+		}
+		if tlsProbeTrust.File() != nil {
+			opts.Roots, err = decodeCertPool(tlsProbeTrust)
+			if err != nil {
+				return err
+			}
+		}
+
+		cmd.SilenceUsage = true
+		ctx, cancel := context.WithTimeout(context.Background(), tlsProbeTimeout)
+		defer cancel()
+		result, err := tlsutil.Probe(ctx, args[0], opts)
+		if err != nil {
+			return err
+		}
+
+		w := &fieldWriter{out: os.Stdout}
+		writeConnectionState(w, &result.State)
+		w.line("Chain:")
+		writeChain(w, result.State.PeerCertificates)
+		writeOCSPStaple(w, &result.State)
+		if result.VerifyError != nil {
+			w.field("Verification", "failed")
+		} else {
+			w.field("Verification", "OK")
+		}
+		if w.err != nil {
+			return w.err
+		}
+		return result.VerifyError
+	},
+}
+
+var tlsServeCommand = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve HTTPS, or TLS echo, on localhost and log each handshake",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		certificate, err := decodeTLSCertificate(tlsServeCert, tlsServeKey)
+		if err != nil {
+			return err
+		}
+		if certificate == nil {
+			certificate, err = temporaryTLSCertificate()
+			if err != nil {
+				return err
+			}
+		}
+		opts := &tlsutil.ServeOptions{
+			Certificate:              *certificate,
+			RequestClientCertificate: tlsServeRequestCert,
+			NextProtos:               tlsServeALPN,
+			Echo:                     tlsServeEcho,
+			Log:                      os.Stderr,
+		}
+		if tlsServeClientCA.File() != nil {
+			opts.ClientCAs, err = decodeCertPool(tlsServeClientCA)
+			if err != nil {
+				return err
+			}
+		}
+
+		listener, err := net.Listen("tcp", tlsServeListen)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(os.Stderr, "Listening on %s\n", listener.Addr())
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return tlsutil.Serve(ctx, listener, opts)
+	},
+}
+
+func init() {
+	tlsCommand.AddCommand(tlsProbeCommand, tlsServeCommand)
+
+	options := tlsProbeCommand.Flags()
+	options.SortFlags = false
+	options.VarP(tlsProbeCert, "cert", "c", "Client certificate, and its chain")
+	options.VarP(tlsProbeKey, "key", "k", "Client certificate key")
+	options.StringVar(&tlsProbeServerName, "sni", "", "Server name to send and verify (default the host)")
+	options.StringSliceVar(&tlsProbeALPN, "alpn", nil, "ALPN protocol to offer")
+	options.Var(tlsProbeTrust, "trust", "Trust bundle to verify the chain with (default system roots)")
+	options.DurationVar(&tlsProbeTimeout, "timeout", 10*time.Second, "Connection timeout")
+
+	options = tlsServeCommand.Flags()
+	options.SortFlags = false
+	options.StringVarP(&tlsServeListen, "listen", "l", "localhost:8443", "Address to listen on")
+	options.VarP(tlsServeCert, "cert", "c", "Server certificate, and its chain (default a temporary certificate)")
+	options.VarP(tlsServeKey, "key", "k", "Server certificate key")
+	options.StringSliceVar(&tlsServeNames, "name", []string{"localhost", "127.0.0.1", "::1"}, "Name of the temporary certificate")
+	options.Var(tlsServeCAOutput, "ca-out", "Write the temporary certificate's CA to a file")
+	options.Var(tlsServeClientCA, "client-ca", "Require client certificates issued by a CA in this bundle")
+	options.BoolVar(&tlsServeRequestCert, "request-client-cert", false, "Request, but don't require or verify, client certificates")
+	options.StringSliceVar(&tlsServeALPN, "alpn", []string{"http/1.1"}, "ALPN protocol to accept")
+	options.BoolVar(&tlsServeEcho, "echo", false, "Serve a raw TLS echo instead of HTTPS")
+}
+
+// temporaryTLSCertificate issues a certificate from a throwaway CA, whose
+// certificate is written to --ca-out for clients to trust.
+func temporaryTLSCertificate() (*tls.Certificate, error) {
+	issuer, err := crypt.NewDevCA()
+	if err != nil {
+		return nil, err
+	}
+	key, cert, err := crypt.IssueDevCertificate(issuer, tlsServeNames, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if tlsServeCAOutput.File() != nil {
+		defer tlsServeCAOutput.File().Close()
+		err = encoding.EncodePEM(tlsServeCAOutput.File(), issuer.Certificate)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &tls.Certificate{
+		Certificate: [][]byte{cert.Raw, issuer.Certificate.Raw},
+		PrivateKey:  key,
+		Leaf:        cert,
+	}, nil
+}
+
+func decodeTLSCertificate(certFile *flags.File, keyFile *flags.File) (*tls.Certificate, error) {
+	if certFile.File() == nil && keyFile.File() == nil {
+		return nil, nil
+	}
+	if certFile.File() == nil || keyFile.File() == nil {
+		return nil, errors.New("a certificate requires both --cert and --key")
+	}
+	certs, err := decodeCertificates(certFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := decodeSigningKey(keyFile.File())
+	if err != nil {
+		return nil, err
+	}
+	certificate := &tls.Certificate{PrivateKey: key, Leaf: certs[0]}
+	for _, cert := range certs {
+		certificate.Certificate = append(certificate.Certificate, cert.Raw)
+	}
+	return certificate, nil
+}
+
+func writeConnectionState(w *fieldWriter, state *tls.ConnectionState) {
+	w.field("Version", tlsutil.VersionName(state.Version))
+	w.field("Cipher Suite", tls.CipherSuiteName(state.CipherSuite))
+	w.field("Server Name", state.ServerName)
+	alpn := state.NegotiatedProtocol
+	if alpn == "" {
+		alpn = "none"
+	}
+	w.field("ALPN", alpn)
+	w.field("Resumed", fmt.Sprint(state.DidResume))
+}
+
+func writeChain(w *fieldWriter, chain []*x509.Certificate) {
+	if len(chain) == 0 {
+		w.line("  none")
+	}
+	for i, cert := range chain {
+		w.line(fmt.Sprintf("  %d: %s", i, cert.Subject))
+		w.field("   Issuer", cert.Issuer.String())
+		w.field("   Names", strings.Join(cert.DNSNames, ", "))
+		w.field("   Not After", cert.NotAfter.UTC().Format(time.RFC3339))
+		w.field("   SHA-256", tlsutil.Fingerprint(cert))
+	}
+}
+
+func writeOCSPStaple(w *fieldWriter, state *tls.ConnectionState) {
+	if len(state.OCSPResponse) == 0 {
+		w.field("OCSP Staple", "none")
+		return
+	}
+	var issuer *x509.Certificate
+	if len(state.PeerCertificates) > 1 {
+		issuer = state.PeerCertificates[1]
+	}
+	response, err := ocsp.ParseResponse(state.OCSPResponse, issuer)
+	if err != nil {
+		w.field("OCSP Staple", fmt.Sprintf("invalid: %v", err))
+		return
+	}
+	w.field("OCSP Staple", fmt.Sprintf("%s, produced %s, next update %s", ocspStatuses[response.Status],
+		response.ProducedAt.UTC().Format(time.RFC3339), response.NextUpdate.UTC().Format(time.RFC3339)))
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+var (
+	verifyKey       = flags.FileRead()
+	verifySignature = flags.FileRead()
+	verifyTrust     = flags.FileRead()
+	verifyHash      string
+	verifyPSS       bool
+	verifyRawECDSA  bool
+	verifyFormat    string
+)
+
+var verifyCommand = &cobra.Command{
+	Use:   "verify [file]",
+	Short: "Verify a detached signature of a file, or data on stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		chain, err := decodePEMFlag(verifyKey)
+		if err != nil {
+			return err
+		}
+		key, err := crypt.PublicKey(chain[0])
+		if err != nil {
+			return err
+		}
+		trusted, err := verifyCertificateChain(chain)
+		if err != nil {
+			return err
+		}
+		signature, err := decodeSignature(verifySignature.File(), verifyFormat)
+		if err != nil {
+			return err
+		}
+		opts, err := signatureOptions(key, verifyHash, verifyPSS, verifyRawECDSA)
+		if err != nil {
+			return err
+		}
+		in, err := openInput(args)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		err = crypt.VerifyReader(in, key, signature, opts)
+		if err != nil {
+			return err
+		}
+		message := "Signature verified"
+		if !trusted {
+			message = "Signature verified, certificate chain NOT verified (no --trust)"
+		}
+		_, err = fmt.Fprintln(os.Stderr, message)
+		return err
+	},
+}
+
+func init() {
+	options := verifyCommand.Flags()
+	options.SortFlags = false
+	options.VarP(verifyKey, "key", "k", "Public key, private key, certificate, or certificate chain")
+	options.VarP(verifySignature, "signature", "s", "Signature file")
+	options.Var(verifyTrust, "trust", "Trusted root bundle to validate a certificate chain against")
+	options.StringVar(&verifyHash, "hash", "", "Hash: sha256, sha384, or sha512 (default sha256, not used for ed25519)")
+	options.BoolVar(&verifyPSS, "pss", false, "Use RSA-PSS instead of PKCS #1 v1.5")
+	options.BoolVar(&verifyRawECDSA, "raw-ecdsa", false, "Decode ECDSA signatures as raw r||s instead of DER")
+	options.StringVarP(&verifyFormat, "format", "f", "base64", "Signature format: base64, hex, or binary")
+
+	_ = verifyCommand.MarkFlagRequired("key")
+	_ = verifyCommand.MarkFlagRequired("signature")
+}
+
+func decodePEMFlag(file *flags.File) (encoding.PEMChain, error) {
+	defer file.File().Close()
+	return encoding.DecodePEM(file.File())
+}
+
+func decodeSignature(file *os.File, format string) ([]byte, error) {
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return encoding.DecodeBytes(format, data)
+}
+
+// verifyCertificateChain verifies a certificate key against --trust, and
+// reports whether it did. Without --trust only the validity of the leaf is
+// checked, as any chain could be made to end in a self-signed root.
+func verifyCertificateChain(chain encoding.PEMChain) (bool, error) {
+	var certs []*x509.Certificate
+	for _, item := range chain {
+		if cert, ok := item.(*x509.Certificate); ok {
+			certs = append(certs, cert)
+		}
+	}
+	if len(certs) == 0 {
+		if verifyTrust.File() != nil {
+			return false, errors.New("a certificate is required to verify against a trust bundle")
+		}
+		return false, nil
+	}
+	if _, ok := chain[0].(*x509.Certificate); !ok {
+		if verifyTrust.File() != nil {
+			return false, errors.New("the key must be a certificate to verify against a trust bundle")
+		}
+		return false, nil
+	}
+
+	if verifyTrust.File() == nil {
+		now := time.Now()
+		if now.Before(certs[0].NotBefore) || now.After(certs[0].NotAfter) {
+			return false, fmt.Errorf("certificate is not valid now: %s", certs[0].Subject)
+		}
+		return false, nil
+	}
+	bundle, err := decodePEMFlag(verifyTrust)
+	if err != nil {
+		return false, err
+	}
+	roots := x509.NewCertPool()
+	for _, item := range bundle {
+		if cert, ok := item.(*x509.Certificate); ok {
+			roots.AddCert(cert)
+		}
+	}
+	return true, crypt.VerifyChain(certs, roots)
+}
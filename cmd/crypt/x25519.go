@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var x25519Command = &cobra.Command{
+	Use:   "x25519",
+	Short: "Generate an X25519 key",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := crypt.GenerateX25519Key()
+		if err != nil {
+			return err
+		}
+
+		return encoding.EncodePEM(os.Stdout, key)
+	},
+}
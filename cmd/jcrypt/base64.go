@@ -1,4 +1,4 @@
-package jcrypt
+package main
 
 import (
 	"encoding/base64"
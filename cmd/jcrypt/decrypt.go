@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/credding/crypt/pkg/prompt"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+)
+
+var (
+	decryptKey          = flags.FileRead()
+	decryptKid          string
+	decryptRaw          bool
+	decryptPasswordFile = flags.FileRead()
+	decryptPasswordEnv  string
+	decryptMinP2C       int
+	decryptMaxP2C       int
+)
+
+var decryptCommand = &cobra.Command{
+	Use:   "decrypt [key]",
+	Short: "Decrypt a JWE given on stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		compact, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		payload, err := decryptPayload(string(compact), args, &jcrypt.DecryptOptions{
+			KeyID:             decryptKid,
+			MinimumPBES2Count: decryptMinP2C,
+			MaximumPBES2Count: decryptMaxP2C,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(payload)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	options := decryptCommand.Flags()
+	options.SortFlags = false
+	options.VarP(decryptKey, "key", "k", "Key file")
+	options.StringVar(&decryptKid, "kid", "", "Key ID")
+	options.BoolVar(&decryptRaw, "raw", false, "Treat the key as raw bytes")
+	options.Var(decryptPasswordFile, "password-file", "Password file")
+	options.StringVar(&decryptPasswordEnv, "password-env", "", "Password environment variable")
+	options.IntVar(&decryptMinP2C, "min-p2c", jcrypt.MinimumPBES2Count, "Minimum accepted PBES2 iteration count")
+	options.IntVar(&decryptMaxP2C, "max-p2c", jcrypt.MaximumPBES2Count, "Maximum accepted PBES2 iteration count")
+}
+
+func decryptPayload(compact string, args []string, opts *jcrypt.DecryptOptions) ([]byte, error) {
+	passwordEncrypted, err := jcrypt.IsPasswordEncrypted(compact)
+	if err != nil {
+		return nil, err
+	}
+	if passwordEncrypted {
+		password, err := prompt.Password(decryptPasswordFile.File(), decryptPasswordEnv, false)
+		if err != nil {
+			return nil, err
+		}
+		return jcrypt.DecryptWithPassword(context.Background(), compact, password, opts)
+	}
+
+	key, err := decodeKey(decryptKey.File(), args, decryptRaw)
+	if err != nil {
+		return nil, err
+	}
+	return jcrypt.Decrypt(context.Background(), compact, key, opts)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/credding/crypt/pkg/prompt"
+	"github.com/spf13/cobra"
+	"github.com/square/go-jose/v3"
+	"os"
+)
+
+var (
+	encryptKey          = flags.FileRead()
+	encryptAlg          string
+	encryptEnc          string
+	encryptKid          string
+	encryptRaw          bool
+	encryptPassword     bool
+	encryptPasswordFile = flags.FileRead()
+	encryptPasswordEnv  string
+	encryptP2C          int
+
+	encryptHeaders []string
+	encryptX5C     bool
+	encryptZip     bool
+)
+
+var encryptCommand = &cobra.Command{
+	Use:   "encrypt [key]",
+	Short: "Generate a JWE given a payload on stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		payload, err := decodePlainPayload(os.Stdin)
+		if err != nil {
+			return err
+		}
+		headers, err := parseHeaders(encryptHeaders)
+		if err != nil {
+			return err
+		}
+
+		compact, err := encryptPayload(payload, args, &jcrypt.EncryptOptions{
+			KeyAlgorithm:      jose.KeyAlgorithm(encryptAlg),
+			ContentEncryption: jose.ContentEncryption(encryptEnc),
+			Headers:           headers,
+			EmbedCertificates: encryptX5C,
+			Compress:          encryptZip,
+			KeyID:             encryptKid,
+			PBES2Count:        encryptP2C,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, compact)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	options := encryptCommand.Flags()
+	options.SortFlags = false
+	options.VarP(encryptKey, "key", "k", "Key file")
+	options.StringVarP(&encryptAlg, "alg", "a", "", "Key algorithm (default auto)")
+	options.StringVarP(&encryptEnc, "enc", "e", string(jcrypt.DefaultContentEncryption), "Encryption algorithm")
+	options.StringVar(&encryptKid, "kid", "", "Key ID")
+	options.BoolVar(&encryptRaw, "raw", false, "Treat the key as raw bytes")
+	options.BoolVarP(&encryptPassword, "password", "p", false, "Encrypt with a password (PBES2)")
+	options.Var(encryptPasswordFile, "password-file", "Password file")
+	options.StringVar(&encryptPasswordEnv, "password-env", "", "Password environment variable")
+	options.IntVar(&encryptP2C, "p2c", jcrypt.DefaultPBES2Count, "PBES2 iteration count")
+	options.StringArrayVarP(&encryptHeaders, "header", "H", nil, "Protected header as name=json")
+	options.BoolVar(&encryptX5C, "x5c", true, "Embed the key certificate chain when available")
+	options.BoolVarP(&encryptZip, "zip", "z", false, "Compress the payload (DEF)")
+}
+
+func encryptPayload(payload []byte, args []string, opts *jcrypt.EncryptOptions) (string, error) {
+	if encryptPassword || encryptPasswordFile.File() != nil || encryptPasswordEnv != "" || jcrypt.IsPBES2(encryptAlg) {
+		password, err := prompt.Password(encryptPasswordFile.File(), encryptPasswordEnv, true)
+		if err != nil {
+			return "", err
+		}
+		return jcrypt.EncryptWithPassword(context.Background(), payload, password, opts)
+	}
+
+	key, err := decodeKey(encryptKey.File(), args, encryptRaw)
+	if err != nil {
+		return "", err
+	}
+	jwk, err := jcrypt.SelectKey(key, encryptKid)
+	if err != nil {
+		return "", err
+	}
+	return jcrypt.Encrypt(context.Background(), payload, jwk, opts)
+}
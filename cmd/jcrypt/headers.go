@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/square/go-jose/v3"
+	"strings"
+)
+
+// reservedHeaders are set from the key and options, and cannot be given
+// with --header.
+var reservedHeaders = map[string]bool{
+	"alg": true, "enc": true, "zip": true, "kid": true, "jwk": true, "x5c": true,
+	"p2c": true, "p2s": true, "epk": true, "apu": true, "apv": true, "iv": true, "tag": true,
+}
+
+func parseHeaders(headers []string) (map[jose.HeaderKey]interface{}, error) {
+	parsed := make(map[jose.HeaderKey]interface{}, len(headers))
+	for _, header := range headers {
+		name, value, err := parseHeader(header)
+		if err != nil {
+			return nil, err
+		}
+		parsed[jose.HeaderKey(name)] = value
+	}
+	return parsed, nil
+}
+
+func parseHeader(header string) (string, interface{}, error) {
+	parts := strings.SplitN(header, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", nil, fmt.Errorf("expected header as name=json, got %s", header)
+	}
+	if reservedHeaders[parts[0]] {
+		return "", nil, fmt.Errorf("header %s is set by jcrypt and cannot be overridden", parts[0])
+	}
+	var value interface{}
+	err := json.Unmarshal([]byte(parts[1]), &value)
+	if err != nil {
+		return "", nil, fmt.Errorf("header %s value is not valid JSON, quote strings as %s='\"value\"': %v", parts[0], parts[0], err)
+	}
+	return parts[0], value, nil
+}
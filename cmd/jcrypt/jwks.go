@@ -1,8 +1,9 @@
-package jcrypt
+package main
 
 import (
 	"encoding/json"
 	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/jcrypt"
 	"github.com/spf13/cobra"
 	"github.com/square/go-jose/v3"
 	"os"
@@ -12,7 +13,7 @@ var jwksCommand = &cobra.Command{
 	Use:   "jwks",
 	Short: "Generate a JWK set given a public, or private key on stdin",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		key, err := keyEncodings.Decode(os.Stdin)
+		key, err := jcrypt.KeyEncodings.Decode(os.Stdin)
 		if err != nil {
 			return err
 		}
@@ -21,7 +22,7 @@ var jwksCommand = &cobra.Command{
 
 		switch key.(type) {
 		case encoding.PEMChain:
-			key, err := pemChainToJWK(key.(encoding.PEMChain))
+			key, err := jcrypt.PEMChainToJWK(key.(encoding.PEMChain))
 			if err != nil {
 				return err
 			}
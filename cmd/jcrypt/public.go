@@ -1,8 +1,9 @@
-package jcrypt
+package main
 
 import (
 	"encoding/json"
 	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/jcrypt"
 	"github.com/spf13/cobra"
 	"github.com/square/go-jose/v3"
 	"os"
@@ -25,13 +26,13 @@ var publicCommand = &cobra.Command{
 
 		switch key.(type) {
 		case encoding.PEMChain:
-			jwk, err := pemChainToJWK(key.(encoding.PEMChain))
+			jwk, err := jcrypt.PEMChainToJWK(key.(encoding.PEMChain))
 			if err != nil {
 				return err
 			}
 			return json.NewEncoder(os.Stdout).Encode(jwk.Public())
 		case *jose.JSONWebKeySet:
-			jwks := getPublicJWKs(key.(*jose.JSONWebKeySet))
+			jwks := jcrypt.PublicKeySet(key.(*jose.JSONWebKeySet))
 			return json.NewEncoder(os.Stdout).Encode(jwks)
 		case *jose.JSONWebKey:
 			jwk := key.(*jose.JSONWebKey).Public()
@@ -40,11 +41,3 @@ var publicCommand = &cobra.Command{
 		return nil
 	},
 }
-
-func getPublicJWKs(jwks *jose.JSONWebKeySet) *jose.JSONWebKeySet {
-	publicJWKs := make([]jose.JSONWebKey, len(jwks.Keys))
-	for i, jwk := range jwks.Keys {
-		publicJWKs[i] = jwk.Public()
-	}
-	return &jose.JSONWebKeySet{Keys: publicJWKs}
-}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+var rawKeyEncodings = encoding.Encodings{
+	encoding.Raw,
+}
+
+func decodePlainPayload(reader io.Reader) ([]byte, error) {
+	payload, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func decodeKey(keyFile *os.File, args []string, raw bool) (interface{}, error) {
+	encodings := jcrypt.KeyEncodings
+	if raw {
+		encodings = rawKeyEncodings
+	}
+	if keyFile != nil {
+		defer keyFile.Close()
+		return encodings.Decode(keyFile)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("key not provided")
+	}
+	return encodings.Unmarshal([]byte(args[0]))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/spf13/cobra"
+	"github.com/square/go-jose/v3"
+	"os"
+)
+
+var (
+	signKey = flags.FileRead()
+	signAlg string
+	signKid string
+	signRaw bool
+
+	signHeaders []string
+	signX5C     bool
+)
+
+var signCommand = &cobra.Command{
+	Use:   "sign [key]",
+	Short: "Generate a JWS given a payload on stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		payload, err := decodePlainPayload(os.Stdin)
+		if err != nil {
+			return err
+		}
+		key, err := decodeKey(signKey.File(), args, signRaw)
+		if err != nil {
+			return err
+		}
+		jwk, err := jcrypt.SelectKey(key, signKid)
+		if err != nil {
+			return err
+		}
+		headers, err := parseHeaders(signHeaders)
+		if err != nil {
+			return err
+		}
+
+		compact, err := jcrypt.Sign(context.Background(), payload, jwk, &jcrypt.SignOptions{
+			Algorithm:         jose.SignatureAlgorithm(signAlg),
+			Headers:           headers,
+			EmbedCertificates: signX5C,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, compact)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	signCommand.Flags().SortFlags = false
+	signCommand.Flags().VarP(signKey, "key", "k", "Key file")
+	signCommand.Flags().StringVarP(&signAlg, "alg", "a", "", "Signature algorithm (default auto)")
+	signCommand.Flags().StringVar(&signKid, "kid", "", "Key ID")
+	signCommand.Flags().BoolVar(&signRaw, "raw", false, "Treat the key as raw bytes")
+	signCommand.Flags().StringArrayVarP(&signHeaders, "header", "H", nil, "Protected header as name=json")
+	signCommand.Flags().BoolVar(&signX5C, "x5c", true, "Embed the key certificate chain when available")
+}
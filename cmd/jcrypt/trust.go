@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"io/ioutil"
+	"os"
+)
+
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"any":             x509.ExtKeyUsageAny,
+	"serverAuth":      x509.ExtKeyUsageServerAuth,
+	"clientAuth":      x509.ExtKeyUsageClientAuth,
+	"codeSigning":     x509.ExtKeyUsageCodeSigning,
+	"emailProtection": x509.ExtKeyUsageEmailProtection,
+	"timeStamping":    x509.ExtKeyUsageTimeStamping,
+	"ocspSigning":     x509.ExtKeyUsageOCSPSigning,
+}
+
+func getTrustOptions(trustFile *os.File, crlFile *os.File, ekus []string) (*jcrypt.TrustOptions, error) {
+	roots, err := decodeTrustBundle(trustFile)
+	if err != nil {
+		return nil, err
+	}
+	keyUsages, err := parseExtKeyUsages(ekus)
+	if err != nil {
+		return nil, err
+	}
+	opts := &jcrypt.TrustOptions{
+		Roots:     roots,
+		KeyUsages: keyUsages,
+	}
+	if crlFile != nil {
+		opts.CRL, err = decodeCRL(crlFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+func decodeTrustBundle(trustFile *os.File) (*x509.CertPool, error) {
+	defer trustFile.Close()
+	chain, err := encoding.DecodePEM(trustFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	for _, cert := range jcrypt.PEMChainCertificates(chain) {
+		roots.AddCert(cert)
+	}
+	if len(roots.Subjects()) == 0 {
+		return nil, errors.New("trust bundle contains no certificates")
+	}
+	return roots, nil
+}
+
+func parseExtKeyUsages(names []string) ([]x509.ExtKeyUsage, error) {
+	usages := make([]x509.ExtKeyUsage, len(names))
+	for i, name := range names {
+		usage, ok := extKeyUsages[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported extended key usage: %s", name)
+		}
+		usages[i] = usage
+	}
+	return usages, nil
+}
+
+func decodeCRL(crlFile *os.File) (*x509.RevocationList, error) {
+	defer crlFile.Close()
+	data, err := ioutil.ReadAll(crlFile)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := encoding.UnmarshalPEM(data)
+	if errors.Is(err, encoding.UnsupportedEncoding) {
+		return x509.ParseRevocationList(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	crl, ok := chain[0].(*x509.RevocationList)
+	if !ok {
+		return nil, errors.New("expected a CRL")
+	}
+	return crl, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/credding/crypt/pkg/flags"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+)
+
+var (
+	verifyKey = flags.FileRead()
+	verifyKid string
+	verifyRaw bool
+
+	verifyTrust = flags.FileRead()
+	verifyCRL   = flags.FileRead()
+	verifyEKU   []string
+)
+
+var verifyCommand = &cobra.Command{
+	Use:   "verify [key]",
+	Short: "Verify a JWS given on stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if verifyTrust.File() == nil && (verifyCRL.File() != nil || cmd.Flags().Changed("eku")) {
+			return errors.New("--crl and --eku require --trust")
+		}
+		compact, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		payload, err := verifyPayload(string(compact), args)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(payload)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	verifyCommand.Flags().SortFlags = false
+	verifyCommand.Flags().VarP(verifyKey, "key", "k", "Key file")
+	verifyCommand.Flags().StringVar(&verifyKid, "kid", "", "Key ID")
+	verifyCommand.Flags().BoolVar(&verifyRaw, "raw", false, "Treat the key as raw bytes")
+	verifyCommand.Flags().Var(verifyTrust, "trust", "Trusted root bundle to validate the x5c chain against")
+	verifyCommand.Flags().Var(verifyCRL, "crl", "CRL to check the x5c chain against")
+	verifyCommand.Flags().StringSliceVar(&verifyEKU, "eku", []string{"any"}, "Required extended key usage")
+}
+
+func verifyPayload(compact string, args []string) ([]byte, error) {
+	if verifyTrust.File() != nil {
+		opts, err := getTrustOptions(verifyTrust.File(), verifyCRL.File(), verifyEKU)
+		if err != nil {
+			return nil, err
+		}
+		payload, _, err := jcrypt.VerifyTrusted(context.Background(), compact, opts)
+		return payload, err
+	}
+
+	key, err := decodeKey(verifyKey.File(), args, verifyRaw)
+	if err != nil {
+		return nil, err
+	}
+	return jcrypt.Verify(context.Background(), compact, key, &jcrypt.VerifyOptions{KeyID: verifyKid})
+}
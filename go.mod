@@ -1,8 +1,15 @@
 module github.com/credding/crypt
 
-go 1.14
+go 1.21
 
 require (
 	github.com/spf13/cobra v1.0.0
 	github.com/square/go-jose/v3 v3.0.0-20200622023058-052237293361
+	golang.org/x/crypto v0.0.0-20190911031432-227b76d455e7
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/spf13/pflag v1.0.3 // indirect
+	golang.org/x/sys v0.0.0-20190412213103-97732733099d // indirect
 )
@@ -0,0 +1,49 @@
+package acme
+
+import (
+	"context"
+	"errors"
+)
+
+// Account is an ACME account resource.
+type Account struct {
+	Status  string   `json:"status"`
+	Contact []string `json:"contact"`
+	Orders  string   `json:"orders"`
+}
+
+type newAccountRequest struct {
+	Contact              []string `json:"contact,omitempty"`
+	TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed,omitempty"`
+	OnlyReturnExisting   bool     `json:"onlyReturnExisting,omitempty"`
+}
+
+// Register creates an account for the client key, or returns the existing
+// one, agreeing to the terms of service. Contacts are URLs such as
+// mailto:admin@example.com.
+func (c *Client) Register(ctx context.Context, contact []string) (*Account, error) {
+	return c.newAccount(ctx, &newAccountRequest{Contact: contact, TermsOfServiceAgreed: true})
+}
+
+// Account finds the existing account of the client key.
+func (c *Client) Account(ctx context.Context) (*Account, error) {
+	return c.newAccount(ctx, &newAccountRequest{OnlyReturnExisting: true})
+}
+
+func (c *Client) newAccount(ctx context.Context, request *newAccountRequest) (*Account, error) {
+	directory, err := c.Directory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.AccountURL = ""
+	account := &Account{}
+	response, err := c.post(ctx, directory.NewAccount, request, account)
+	if err != nil {
+		return nil, err
+	}
+	c.AccountURL = response.Header.Get("Location")
+	if c.AccountURL == "" {
+		return nil, errors.New("acme: server did not return the account URL")
+	}
+	return account, nil
+}
@@ -0,0 +1,268 @@
+// Package acme is a client for ACME (RFC 8555) certificate authorities,
+// signing requests with jcrypt.
+package acme
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/square/go-jose/v3"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	problemBadNonce  = "urn:ietf:params:acme:error:badNonce"
+	maxResponseSize  = 1 << 20
+	jsonContentType  = "application/jose+json"
+	defaultRetryWait = time.Second
+)
+
+// Directory lists the resource URLs of an ACME server.
+type Directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+	RevokeCert string `json:"revokeCert"`
+	KeyChange  string `json:"keyChange"`
+	Meta       struct {
+		TermsOfService string `json:"termsOfService"`
+	} `json:"meta"`
+}
+
+// Problem is an ACME error document.
+type Problem struct {
+	Type        string    `json:"type"`
+	Detail      string    `json:"detail"`
+	Status      int       `json:"status"`
+	Subproblems []Problem `json:"subproblems"`
+}
+
+func (p *Problem) Error() string {
+	message := fmt.Sprintf("acme: %s: %s", p.Type, p.Detail)
+	for _, subproblem := range p.Subproblems {
+		message += "; " + subproblem.Detail
+	}
+	return message
+}
+
+// Client makes requests to an ACME server with an account key.
+type Client struct {
+	DirectoryURL string
+	// Key is the account private key.
+	Key *jose.JSONWebKey
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// AccountURL is set by Register, or found by Account.
+	AccountURL string
+
+	directory *Directory
+	nonces    []string
+	mutex     sync.Mutex
+}
+
+// Directory fetches, and caches, the server directory.
+func (c *Client) Directory(ctx context.Context) (*Directory, error) {
+	if c.directory != nil {
+		return c.directory, nil
+	}
+	response, err := c.do(ctx, http.MethodGet, c.DirectoryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	directory := &Directory{}
+	err = decodeResponse(response, directory)
+	if err != nil {
+		return nil, err
+	}
+	c.directory = directory
+	return directory, nil
+}
+
+// post sends a JWS signed request to url, decoding the JSON response into
+// result if it is not nil. A nil payload sends a POST-as-GET.
+func (c *Client) post(ctx context.Context, url string, payload interface{}, result interface{}) (*http.Response, error) {
+	body := []byte{}
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var response *http.Response
+	for attempt := 0; ; attempt++ {
+		jws, err := c.sign(ctx, url, body)
+		if err != nil {
+			return nil, err
+		}
+		response, err = c.do(ctx, http.MethodPost, url, strings.NewReader(jws))
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode < 400 {
+			break
+		}
+		problem := responseProblem(response)
+		response.Body.Close()
+		if problem.Type != problemBadNonce || attempt > 0 {
+			return nil, problem
+		}
+	}
+	if result == nil {
+		return response, nil
+	}
+	defer response.Body.Close()
+	return response, decodeResponse(response, result)
+}
+
+func (c *Client) sign(ctx context.Context, url string, payload []byte) (string, error) {
+	nonce, err := c.nonce(ctx)
+	if err != nil {
+		return "", err
+	}
+	key := *c.Key
+	key.KeyID = c.AccountURL
+	opts := &jcrypt.SignOptions{
+		Headers: map[jose.HeaderKey]interface{}{
+			"nonce": nonce,
+			"url":   url,
+		},
+		EmbedJWK: c.AccountURL == "",
+	}
+	signer, err := jcrypt.NewSigner(&key, opts)
+	if err != nil {
+		return "", err
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return jws.FullSerialize(), nil
+}
+
+func (c *Client) nonce(ctx context.Context) (string, error) {
+	nonce := c.popNonce()
+	if nonce != "" {
+		return nonce, nil
+	}
+
+	directory, err := c.Directory(ctx)
+	if err != nil {
+		return "", err
+	}
+	// do pools the Replay-Nonce of the response, so it is taken back out
+	// rather than left to be used twice.
+	response, err := c.do(ctx, http.MethodHead, directory.NewNonce, nil)
+	if err != nil {
+		return "", err
+	}
+	response.Body.Close()
+	nonce = c.popNonce()
+	if nonce == "" {
+		return "", errors.New("acme: server did not provide a nonce")
+	}
+	return nonce, nil
+}
+
+func (c *Client) popNonce() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.nonces) == 0 {
+		return ""
+	}
+	nonce := c.nonces[len(c.nonces)-1]
+	c.nonces = c.nonces[:len(c.nonces)-1]
+	return nonce
+}
+
+func (c *Client) do(ctx context.Context, method string, url string, body io.Reader) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", jsonContentType)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	nonce := response.Header.Get("Replay-Nonce")
+	if nonce != "" {
+		c.mutex.Lock()
+		c.nonces = append(c.nonces, nonce)
+		c.mutex.Unlock()
+	}
+	if method != http.MethodPost && response.StatusCode >= 400 {
+		defer response.Body.Close()
+		return nil, responseProblem(response)
+	}
+	return response, nil
+}
+
+func decodeResponse(response *http.Response, result interface{}) error {
+	data, err := ioutil.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return fmt.Errorf("acme: invalid response from %s: %w", response.Request.URL, err)
+	}
+	return nil
+}
+
+func responseProblem(response *http.Response) *Problem {
+	data, _ := ioutil.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	problem := &Problem{}
+	err := json.Unmarshal(data, problem)
+	if err != nil || problem.Type == "" {
+		return &Problem{Status: response.StatusCode, Detail: strings.TrimSpace(string(bytes.TrimSpace(data))), Type: response.Status}
+	}
+	return problem
+}
+
+// retryAfter returns how long to wait before polling again.
+func retryAfter(response *http.Response) time.Duration {
+	if response == nil {
+		return defaultRetryWait
+	}
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return defaultRetryWait
+	}
+	var seconds int
+	_, err := fmt.Sscanf(value, "%d", &seconds)
+	if err != nil || seconds < 1 {
+		return defaultRetryWait
+	}
+	if seconds > 60 {
+		seconds = 60
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,465 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"github.com/square/go-jose/v3"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer is a minimal ACME server that checks every signed request and
+// validates http-01 challenges through responder.
+type testServer struct {
+	server    *httptest.Server
+	issuer    *crypt.Issuer
+	responder *HTTP01Responder
+
+	mutex          sync.Mutex
+	nonce          int
+	nonces         map[string]bool
+	rejectNonce    bool
+	omitPOSTNonce  bool
+	badNonces      int
+	accounts       map[string]*jose.JSONWebKey
+	order          *Order
+	authorization  *Authorization
+	certificatePEM []byte
+}
+
+func newTestServer(t *testing.T, responder *HTTP01Responder) *testServer {
+	issuer, err := crypt.NewDevCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{
+		issuer:      issuer,
+		responder:   responder,
+		nonces:      make(map[string]bool),
+		rejectNonce: true,
+		accounts:    make(map[string]*jose.JSONWebKey),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *testServer) url(path string) string {
+	return s.server.URL + path
+}
+
+func (s *testServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if r.Method != http.MethodPost || !s.omitPOSTNonce {
+		w.Header().Set("Replay-Nonce", s.newNonce())
+	}
+
+	switch r.URL.Path {
+	case "/directory":
+		writeJSON(w, http.StatusOK, &Directory{
+			NewNonce:   s.url("/new-nonce"),
+			NewAccount: s.url("/new-account"),
+			NewOrder:   s.url("/new-order"),
+		})
+		return
+	case "/new-nonce":
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, "malformed", "expected a POST")
+		return
+	}
+	key, payload, problem := s.verify(r)
+	if problem != nil {
+		writeJSON(w, http.StatusBadRequest, problem)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/new-account":
+		s.newAccount(w, key, payload)
+	case "/new-order":
+		s.newOrder(w, payload)
+	case "/order":
+		writeJSON(w, http.StatusOK, s.order)
+	case "/authz":
+		writeJSON(w, http.StatusOK, s.authorization)
+	case "/challenge":
+		s.validate(w, key)
+	case "/finalize":
+		s.finalize(w, payload)
+	case "/cert":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		_, _ = w.Write(s.certificatePEM)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *testServer) newNonce() string {
+	s.nonce++
+	nonce := fmt.Sprintf("nonce-%d", s.nonce)
+	s.nonces[nonce] = true
+	return nonce
+}
+
+// verify checks the JWS of a request, returning the account key and payload.
+func (s *testServer) verify(r *http.Request) (*jose.JSONWebKey, []byte, *Problem) {
+	if r.Header.Get("Content-Type") != jsonContentType {
+		return nil, nil, &Problem{Type: "urn:ietf:params:acme:error:malformed", Detail: "wrong content type"}
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, &Problem{Type: "urn:ietf:params:acme:error:malformed", Detail: err.Error()}
+	}
+	jws, err := jose.ParseSigned(string(body))
+	if err != nil || len(jws.Signatures) != 1 {
+		return nil, nil, &Problem{Type: "urn:ietf:params:acme:error:malformed", Detail: "invalid JWS"}
+	}
+	header := jws.Signatures[0].Protected
+
+	if !s.nonces[header.Nonce] || s.rejectNonce {
+		s.rejectNonce = false
+		s.badNonces++
+		return nil, nil, &Problem{Type: problemBadNonce, Detail: "invalid nonce " + header.Nonce}
+	}
+	delete(s.nonces, header.Nonce)
+	if url, _ := header.ExtraHeaders["url"].(string); url != s.url(r.URL.Path) {
+		return nil, nil, &Problem{Type: "urn:ietf:params:acme:error:unauthorized", Detail: "url header " + url + " does not match"}
+	}
+
+	key := header.JSONWebKey
+	switch {
+	case r.URL.Path == "/new-account":
+		if key == nil || header.KeyID != "" {
+			return nil, nil, &Problem{Type: "urn:ietf:params:acme:error:malformed", Detail: "expected an embedded jwk"}
+		}
+	case key != nil:
+		return nil, nil, &Problem{Type: "urn:ietf:params:acme:error:malformed", Detail: "unexpected embedded jwk"}
+	default:
+		key = s.accounts[header.KeyID]
+		if key == nil {
+			return nil, nil, &Problem{Type: "urn:ietf:params:acme:error:accountDoesNotExist", Detail: "unknown kid " + header.KeyID}
+		}
+	}
+	payload, err := jws.Verify(key)
+	if err != nil {
+		return nil, nil, &Problem{Type: "urn:ietf:params:acme:error:unauthorized", Detail: err.Error()}
+	}
+	return key, payload, nil
+}
+
+func (s *testServer) newAccount(w http.ResponseWriter, key *jose.JSONWebKey, payload []byte) {
+	request := &newAccountRequest{}
+	err := json.Unmarshal(payload, request)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "malformed", err.Error())
+		return
+	}
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "badPublicKey", err.Error())
+		return
+	}
+	accountURL := s.url("/account/" + base64.RawURLEncoding.EncodeToString(thumbprint))
+	status := http.StatusOK
+	if s.accounts[accountURL] == nil {
+		if request.OnlyReturnExisting {
+			writeProblem(w, http.StatusBadRequest, "accountDoesNotExist", "no account for the key")
+			return
+		}
+		s.accounts[accountURL] = key
+		status = http.StatusCreated
+	}
+	w.Header().Set("Location", accountURL)
+	writeJSON(w, status, &Account{Status: "valid", Contact: request.Contact})
+}
+
+func (s *testServer) newOrder(w http.ResponseWriter, payload []byte) {
+	request := &struct {
+		Identifiers []Identifier `json:"identifiers"`
+	}{}
+	err := json.Unmarshal(payload, request)
+	if err != nil || len(request.Identifiers) != 1 {
+		writeProblem(w, http.StatusBadRequest, "malformed", "expected one identifier")
+		return
+	}
+	s.authorization = &Authorization{
+		Status:     "pending",
+		Identifier: request.Identifiers[0],
+		Challenges: []Challenge{
+			{Type: "dns-01", URL: s.url("/dns-challenge"), Status: "pending", Token: "dns-token"},
+			{Type: "http-01", URL: s.url("/challenge"), Status: "pending", Token: "http-token"},
+		},
+	}
+	s.order = &Order{
+		Status:         "pending",
+		Identifiers:    request.Identifiers,
+		Authorizations: []string{s.url("/authz")},
+		Finalize:       s.url("/finalize"),
+	}
+	w.Header().Set("Location", s.url("/order"))
+	writeJSON(w, http.StatusCreated, s.order)
+}
+
+// validate fetches the key authorization from the responder, as a server
+// would over HTTP from the identifier.
+func (s *testServer) validate(w http.ResponseWriter, key *jose.JSONWebKey) {
+	challenge := &s.authorization.Challenges[1]
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "badPublicKey", err.Error())
+		return
+	}
+	expected := challenge.Token + "." + base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "http://"+s.authorization.Identifier.Value+http01Path+challenge.Token, nil)
+	s.responder.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		challenge.Status = "invalid"
+		challenge.Error = &Problem{Type: "urn:ietf:params:acme:error:unauthorized", Detail: "got " + recorder.Body.String()}
+		s.authorization.Status = "invalid"
+		s.order.Status = "invalid"
+	} else {
+		challenge.Status = "valid"
+		s.authorization.Status = "valid"
+		s.order.Status = "ready"
+	}
+	writeJSON(w, http.StatusOK, challenge)
+}
+
+func (s *testServer) finalize(w http.ResponseWriter, payload []byte) {
+	if s.order.Status != "ready" {
+		writeProblem(w, http.StatusForbidden, "orderNotReady", "order is "+s.order.Status)
+		return
+	}
+	request := map[string]string{}
+	err := json.Unmarshal(payload, &request)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "malformed", err.Error())
+		return
+	}
+	der, err := base64.RawURLEncoding.DecodeString(request["csr"])
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "badCSR", err.Error())
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err == nil {
+		err = csr.CheckSignature()
+	}
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "badCSR", err.Error())
+		return
+	}
+	if len(csr.DNSNames) != 1 || csr.DNSNames[0] != s.authorization.Identifier.Value {
+		writeProblem(w, http.StatusBadRequest, "badCSR", "names do not match the order")
+		return
+	}
+
+	serialNumber, err := crypt.SerialNumber()
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, "serverInternal", err.Error())
+		return
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     csr.DNSNames,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certRaw, err := x509.CreateCertificate(rand.Reader, template, s.issuer.Certificate, csr.PublicKey, s.issuer.Key)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, "serverInternal", err.Error())
+		return
+	}
+	s.certificatePEM = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certRaw}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.issuer.Certificate.Raw})...)
+	s.order.Status = "valid"
+	s.order.Certificate = s.url("/cert")
+	writeJSON(w, http.StatusOK, s.order)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	if problem, ok := value.(*Problem); ok {
+		w.Header().Set("Content-Type", "application/problem+json")
+		problem.Status = status
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeProblem(w http.ResponseWriter, status int, problemType string, detail string) {
+	writeJSON(w, status, &Problem{Type: "urn:ietf:params:acme:error:" + problemType, Detail: detail})
+}
+
+func newTestKey(t *testing.T) *jose.JSONWebKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &jose.JSONWebKey{Key: key}
+}
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	responder := NewHTTP01Responder()
+	server := newTestServer(t, responder)
+	client := &Client{DirectoryURL: server.url("/directory"), Key: newTestKey(t)}
+
+	_, err := client.Account(ctx)
+	if err == nil {
+		t.Fatal("Account succeeded before Register")
+	}
+	if problem, ok := err.(*Problem); !ok || !strings.HasSuffix(problem.Type, ":accountDoesNotExist") {
+		t.Fatalf("Account: %v, want accountDoesNotExist", err)
+	}
+
+	account, err := client.Register(ctx, []string{"mailto:admin@example.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Status != "valid" || len(account.Contact) != 1 {
+		t.Errorf("Register returned %+v", account)
+	}
+	accountURL := client.AccountURL
+	if !strings.HasPrefix(accountURL, server.url("/account/")) {
+		t.Fatalf("AccountURL = %q", accountURL)
+	}
+	_, err = client.Account(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.AccountURL != accountURL {
+		t.Errorf("Account found %q, want %q", client.AccountURL, accountURL)
+	}
+
+	order, err := client.NewOrder(ctx, Identifiers([]string{"www.example.test"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.URL != server.url("/order") || order.Status != "pending" {
+		t.Fatalf("NewOrder returned %+v", order)
+	}
+	err = client.AuthorizeHTTP01(ctx, order, responder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err = client.GetOrder(ctx, order.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "ready" {
+		t.Fatalf("order is %s after authorization, want ready", order.Status)
+	}
+
+	certificateKey := newTestKey(t)
+	csrRaw, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "www.example.test"},
+		DNSNames: []string{"www.example.test"},
+	}, certificateKey.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.ParseCertificateRequest(csrRaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err = client.Finalize(ctx, order, csr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs, err := client.Certificate(ctx, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 2 {
+		t.Fatalf("Certificate returned %d certificates, want 2", len(certs))
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(server.issuer.Certificate)
+	_, err = certs[0].Verify(x509.VerifyOptions{DNSName: "www.example.test", Roots: roots})
+	if err != nil {
+		t.Error(err)
+	}
+	if !certs[0].PublicKey.(*ecdsa.PublicKey).Equal(certificateKey.Public().Key) {
+		t.Error("certificate is not for the CSR key")
+	}
+	if server.badNonces != 1 {
+		t.Errorf("server rejected %d nonces, want only the injected one", server.badNonces)
+	}
+}
+
+func TestClientWithoutPOSTNonces(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server := newTestServer(t, NewHTTP01Responder())
+	server.rejectNonce = false
+	server.omitPOSTNonce = true
+	client := &Client{DirectoryURL: server.url("/directory"), Key: newTestKey(t)}
+
+	// Every signed request needs a fresh nonce from newNonce.
+	_, err := client.Register(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := client.NewOrder(ctx, Identifiers([]string{"www.example.test"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.GetOrder(ctx, order.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.badNonces != 0 {
+		t.Errorf("server rejected %d nonces, want none", server.badNonces)
+	}
+}
+
+func TestAuthorizeHTTP01Invalid(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server := newTestServer(t, NewHTTP01Responder())
+	client := &Client{DirectoryURL: server.url("/directory"), Key: newTestKey(t)}
+	_, err := client.Register(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := client.NewOrder(ctx, Identifiers([]string{"www.example.test"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server validates through a responder the client does not serve.
+	err = client.AuthorizeHTTP01(ctx, order, NewHTTP01Responder())
+	if err == nil {
+		t.Fatal("AuthorizeHTTP01 succeeded, want an invalid authorization")
+	}
+	if !strings.Contains(err.Error(), "is invalid") {
+		t.Errorf("AuthorizeHTTP01: %v", err)
+	}
+}
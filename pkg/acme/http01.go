@@ -0,0 +1,102 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const http01Path = "/.well-known/acme-challenge/"
+
+// KeyAuthorization returns the key authorization of a challenge token for
+// the client key.
+func (c *Client) KeyAuthorization(token string) (string, error) {
+	public := c.Key.Public()
+	thumbprint, err := public.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return token + "." + base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// HTTP01Responder serves http-01 challenge responses.
+type HTTP01Responder struct {
+	tokens map[string]string
+	mutex  sync.RWMutex
+}
+
+// NewHTTP01Responder returns a responder with no challenges.
+func NewHTTP01Responder() *HTTP01Responder {
+	return &HTTP01Responder{tokens: make(map[string]string)}
+}
+
+// Add serves keyAuthorization for token.
+func (r *HTTP01Responder) Add(token string, keyAuthorization string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tokens[token] = keyAuthorization
+}
+
+// Remove stops serving token.
+func (r *HTTP01Responder) Remove(token string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.tokens, token)
+}
+
+func (r *HTTP01Responder) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	if !strings.HasPrefix(request.URL.Path, http01Path) {
+		http.NotFound(w, request)
+		return
+	}
+	r.mutex.RLock()
+	keyAuthorization, ok := r.tokens[strings.TrimPrefix(request.URL.Path, http01Path)]
+	r.mutex.RUnlock()
+	if !ok {
+		http.NotFound(w, request)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = fmt.Fprint(w, keyAuthorization)
+}
+
+// AuthorizeHTTP01 completes the pending http-01 challenges of an order
+// through responder, waiting until every authorization is valid.
+func (c *Client) AuthorizeHTTP01(ctx context.Context, order *Order, responder *HTTP01Responder) error {
+	for _, url := range order.Authorizations {
+		authorization, err := c.GetAuthorization(ctx, url)
+		if err != nil {
+			return err
+		}
+		if authorization.Status == "valid" {
+			continue
+		}
+		var challenge *Challenge
+		for i := range authorization.Challenges {
+			if authorization.Challenges[i].Type == "http-01" {
+				challenge = &authorization.Challenges[i]
+			}
+		}
+		if challenge == nil {
+			return fmt.Errorf("acme: no http-01 challenge for %s", authorization.Identifier.Value)
+		}
+		keyAuthorization, err := c.KeyAuthorization(challenge.Token)
+		if err != nil {
+			return err
+		}
+		responder.Add(challenge.Token, keyAuthorization)
+		err = c.Accept(ctx, challenge)
+		if err == nil {
+			_, err = c.WaitAuthorization(ctx, url)
+		}
+		responder.Remove(challenge.Token)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
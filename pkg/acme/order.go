@@ -0,0 +1,182 @@
+package acme
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/encoding"
+	"io"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+// Identifier is a name a certificate is ordered for.
+type Identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Order is an ACME order resource.
+type Order struct {
+	URL            string       `json:"-"`
+	Status         string       `json:"status"`
+	Expires        *time.Time   `json:"expires,omitempty"`
+	Identifiers    []Identifier `json:"identifiers"`
+	Authorizations []string     `json:"authorizations"`
+	Finalize       string       `json:"finalize"`
+	Certificate    string       `json:"certificate,omitempty"`
+	Error          *Problem     `json:"error,omitempty"`
+}
+
+// Authorization is an ACME authorization resource.
+type Authorization struct {
+	URL        string      `json:"-"`
+	Status     string      `json:"status"`
+	Identifier Identifier  `json:"identifier"`
+	Challenges []Challenge `json:"challenges"`
+	Wildcard   bool        `json:"wildcard"`
+}
+
+// Challenge is an ACME challenge resource.
+type Challenge struct {
+	Type   string   `json:"type"`
+	URL    string   `json:"url"`
+	Status string   `json:"status"`
+	Token  string   `json:"token"`
+	Error  *Problem `json:"error,omitempty"`
+}
+
+// Identifiers returns DNS, or IP for addresses, identifiers for names.
+func Identifiers(names []string) []Identifier {
+	identifiers := make([]Identifier, len(names))
+	for i, name := range names {
+		identifiers[i] = Identifier{Type: "dns", Value: name}
+		if net.ParseIP(name) != nil {
+			identifiers[i].Type = "ip"
+		}
+	}
+	return identifiers
+}
+
+// NewOrder orders a certificate for identifiers.
+func (c *Client) NewOrder(ctx context.Context, identifiers []Identifier) (*Order, error) {
+	directory, err := c.Directory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order := &Order{}
+	response, err := c.post(ctx, directory.NewOrder, map[string]interface{}{"identifiers": identifiers}, order)
+	if err != nil {
+		return nil, err
+	}
+	order.URL = response.Header.Get("Location")
+	return order, nil
+}
+
+// GetOrder fetches an order.
+func (c *Client) GetOrder(ctx context.Context, url string) (*Order, error) {
+	order := &Order{URL: url}
+	_, err := c.post(ctx, url, nil, order)
+	return order, err
+}
+
+// GetAuthorization fetches an authorization.
+func (c *Client) GetAuthorization(ctx context.Context, url string) (*Authorization, error) {
+	authorization := &Authorization{URL: url}
+	_, err := c.post(ctx, url, nil, authorization)
+	return authorization, err
+}
+
+// Accept tells the server a challenge is ready to be validated.
+func (c *Client) Accept(ctx context.Context, challenge *Challenge) error {
+	_, err := c.post(ctx, challenge.URL, struct{}{}, &Challenge{})
+	return err
+}
+
+// WaitAuthorization polls an authorization until it is valid, failing if it
+// becomes invalid.
+func (c *Client) WaitAuthorization(ctx context.Context, url string) (*Authorization, error) {
+	for {
+		authorization := &Authorization{URL: url}
+		response, err := c.post(ctx, url, nil, authorization)
+		if err != nil {
+			return nil, err
+		}
+		switch authorization.Status {
+		case "valid":
+			return authorization, nil
+		case "pending", "processing":
+		default:
+			return nil, authorizationError(authorization)
+		}
+		err = sleep(ctx, retryAfter(response))
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Finalize submits the certificate signing request for a ready order, and
+// polls until the certificate is issued.
+func (c *Client) Finalize(ctx context.Context, order *Order, csr *x509.CertificateRequest) (*Order, error) {
+	request := map[string]string{"csr": base64.RawURLEncoding.EncodeToString(csr.Raw)}
+	finalized := &Order{URL: order.URL}
+	response, err := c.post(ctx, order.Finalize, request, finalized)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch finalized.Status {
+		case "valid":
+			return finalized, nil
+		case "processing", "ready", "pending":
+		default:
+			if finalized.Error != nil {
+				return nil, finalized.Error
+			}
+			return nil, fmt.Errorf("acme: order is %s", finalized.Status)
+		}
+		err = sleep(ctx, retryAfter(response))
+		if err != nil {
+			return nil, err
+		}
+		finalized = &Order{URL: order.URL}
+		response, err = c.post(ctx, order.URL, nil, finalized)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Certificate downloads the issued certificate chain of a valid order.
+func (c *Client) Certificate(ctx context.Context, order *Order) ([]*x509.Certificate, error) {
+	if order.Certificate == "" {
+		return nil, errors.New("acme: order has no certificate")
+	}
+	response, err := c.post(ctx, order.Certificate, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	certs := encoding.ExtractCertificates(data)
+	if len(certs) == 0 {
+		return nil, errors.New("acme: no certificates in the response")
+	}
+	return certs, nil
+}
+
+func authorizationError(authorization *Authorization) error {
+	for _, challenge := range authorization.Challenges {
+		if challenge.Error != nil {
+			return fmt.Errorf("acme: authorization for %s is %s: %w", authorization.Identifier.Value, authorization.Status, challenge.Error)
+		}
+	}
+	return fmt.Errorf("acme: authorization for %s is %s", authorization.Identifier.Value, authorization.Status)
+}
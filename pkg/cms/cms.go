@@ -0,0 +1,196 @@
+// Package cms creates and verifies CMS (PKCS #7) SignedData, and encrypts and
+// decrypts EnvelopedData to RSA recipient certificates, as described in
+// RFC 5652.
+package cms
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+
+	oidAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidRSAEncryption   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSHA384WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+var (
+	UnsupportedContentType = errors.New("unsupported cms content type")
+	NoMatchingRecipient    = errors.New("no recipient matches the certificate")
+	// DecryptionFailed is returned for every key unwrap, decryption and
+	// padding failure, so they cannot be told apart.
+	DecryptionFailed = errors.New("cms decryption failed")
+)
+
+var digestAlgorithms = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA256: oidSHA256,
+	crypto.SHA384: oidSHA384,
+	crypto.SHA512: oidSHA512,
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"`
+}
+
+type rawContent struct {
+	Raw asn1.RawContent
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+func marshalContentInfo(contentType asn1.ObjectIdentifier, content interface{}) ([]byte, error) {
+	encoded, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: contentType,
+		Content:     explicit(0, encoded),
+	})
+}
+
+func parseContentInfo(der []byte, contentType asn1.ObjectIdentifier, content interface{}) error {
+	var info contentInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.New("trailing data after cms content")
+	}
+	if !info.ContentType.Equal(contentType) {
+		return fmt.Errorf("%w: %v", UnsupportedContentType, info.ContentType)
+	}
+	inner, err := unwrapExplicit(info.Content, 0)
+	if err != nil {
+		return err
+	}
+	_, err = asn1.Unmarshal(inner, content)
+	return err
+}
+
+func explicit(tag int, encoded []byte) asn1.RawValue {
+	return asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        tag,
+		IsCompound: true,
+		Bytes:      encoded,
+	}
+}
+
+func unwrapExplicit(value asn1.RawValue, tag int) ([]byte, error) {
+	if value.Class != asn1.ClassContextSpecific || value.Tag != tag || !value.IsCompound {
+		return nil, errors.New("missing cms content")
+	}
+	return value.Bytes, nil
+}
+
+func marshalSet(items [][]byte) ([]byte, error) {
+	var content []byte
+	for _, item := range items {
+		content = append(content, item...)
+	}
+	return asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      content,
+	})
+}
+
+func certificateID(cert *x509.Certificate) issuerAndSerialNumber {
+	return issuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+		SerialNumber: cert.SerialNumber,
+	}
+}
+
+func (id issuerAndSerialNumber) matches(cert *x509.Certificate) bool {
+	return string(id.Issuer.FullBytes) == string(cert.RawIssuer) && id.SerialNumber.Cmp(cert.SerialNumber) == 0
+}
+
+func marshalCertificates(certs []*x509.Certificate) (rawContent, error) {
+	if len(certs) == 0 {
+		return rawContent{}, nil
+	}
+	items := make([][]byte, len(certs))
+	for i, cert := range certs {
+		items[i] = cert.Raw
+	}
+	set, err := marshalSet(items)
+	if err != nil {
+		return rawContent{}, err
+	}
+	return rawContent{Raw: set}, nil
+}
+
+func parseCertificates(raw rawContent) ([]*x509.Certificate, error) {
+	if len(raw.Raw) == 0 {
+		return nil, nil
+	}
+	var set asn1.RawValue
+	_, err := asn1.Unmarshal(raw.Raw, &set)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificates(set.Bytes)
+}
+
+func algorithmIdentifier(oid asn1.ObjectIdentifier) pkix.AlgorithmIdentifier {
+	return pkix.AlgorithmIdentifier{
+		Algorithm:  oid,
+		Parameters: asn1.NullRawValue,
+	}
+}
+
+func digestHash(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	for hash, digestOID := range digestAlgorithms {
+		if digestOID.Equal(oid) {
+			return hash, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported digest algorithm: %v", oid)
+}
+
+// ParseCertificates returns the certificates embedded in a CMS SignedData
+// structure, such as a certs-only PKCS #7 bundle.
+func ParseCertificates(der []byte) ([]*x509.Certificate, error) {
+	sd, err := ParseSignedData(der)
+	if err != nil {
+		return nil, err
+	}
+	return sd.Certificates, nil
+}
@@ -0,0 +1,175 @@
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+type envelopedData struct {
+	Version              int
+	RecipientInfos       []keyTransRecipientInfo `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type keyTransRecipientInfo struct {
+	Version                int
+	RID                    issuerAndSerialNumber
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"optional"`
+}
+
+// Encrypt creates a DER encoded CMS EnvelopedData of content, encrypted with
+// AES-256-CBC to each RSA recipient certificate.
+func Encrypt(content []byte, recipients []*x509.Certificate) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients provided")
+	}
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	_, err = rand.Read(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]keyTransRecipientInfo, len(recipients))
+	for i, recipient := range recipients {
+		public, ok := recipient.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported recipient key type: %v", reflect.TypeOf(recipient.PublicKey))
+		}
+		encryptedKey, err := rsa.EncryptPKCS1v15(rand.Reader, public, key)
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = keyTransRecipientInfo{
+			RID:                    certificateID(recipient),
+			KeyEncryptionAlgorithm: algorithmIdentifier(oidRSAEncryption),
+			EncryptedKey:           encryptedKey,
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padded := pad(content, aes.BlockSize)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(padded, padded)
+	ivParameter, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalContentInfo(oidEnvelopedData, envelopedData{
+		RecipientInfos: infos,
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType: oidData,
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidAES256CBC,
+				Parameters: asn1.RawValue{FullBytes: ivParameter},
+			},
+			EncryptedContent: asn1.RawValue{
+				Class: asn1.ClassContextSpecific,
+				Tag:   0,
+				Bytes: padded,
+			},
+		},
+	})
+}
+
+// Decrypt decrypts a DER encoded CMS EnvelopedData for the recipient
+// certificate with its private key.
+func Decrypt(der []byte, cert *x509.Certificate, key crypto.Decrypter) ([]byte, error) {
+	var ed envelopedData
+	err := parseContentInfo(der, oidEnvelopedData, &ed)
+	if err != nil {
+		return nil, err
+	}
+
+	var recipient *keyTransRecipientInfo
+	for i, info := range ed.RecipientInfos {
+		if info.RID.matches(cert) {
+			recipient = &ed.RecipientInfos[i]
+			break
+		}
+	}
+	if recipient == nil {
+		return nil, NoMatchingRecipient
+	}
+	if !recipient.KeyEncryptionAlgorithm.Algorithm.Equal(oidRSAEncryption) {
+		return nil, fmt.Errorf("unsupported key encryption algorithm: %v", recipient.KeyEncryptionAlgorithm.Algorithm)
+	}
+	info := ed.EncryptedContentInfo
+	algorithm := info.ContentEncryptionAlgorithm.Algorithm
+	var keySize int
+	switch {
+	case algorithm.Equal(oidAES256CBC):
+		keySize = 32
+	case algorithm.Equal(oidAES128CBC):
+		keySize = 16
+	default:
+		return nil, fmt.Errorf("unsupported content encryption algorithm: %v", algorithm)
+	}
+	var iv []byte
+	_, err = asn1.Unmarshal(info.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := append([]byte{}, info.EncryptedContent.Bytes...)
+	if len(iv) != aes.BlockSize || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted content")
+	}
+
+	// With SessionKeyLen set, a bad PKCS #1 v1.5 block yields a random key
+	// rather than an error, and the content then fails to unpad.
+	contentKey, err := key.Decrypt(rand.Reader, recipient.EncryptedKey, &rsa.PKCS1v15DecryptOptions{SessionKeyLen: keySize})
+	if err != nil || len(contentKey) != keySize {
+		return nil, DecryptionFailed
+	}
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, DecryptionFailed
+	}
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+	return unpad(ciphertext, aes.BlockSize)
+}
+
+func pad(data []byte, size int) []byte {
+	n := size - len(data)%size
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+// unpad removes PKCS #7 padding in constant time.
+func unpad(data []byte, size int) ([]byte, error) {
+	n := data[len(data)-1]
+	good := subtle.ConstantTimeLessOrEq(1, int(n)) & subtle.ConstantTimeLessOrEq(int(n), size)
+	for i := 1; i <= size && i <= len(data); i++ {
+		inPadding := subtle.ConstantTimeLessOrEq(i, int(n))
+		matches := subtle.ConstantTimeByteEq(data[len(data)-i], n)
+		good &= subtle.ConstantTimeSelect(inPadding, matches, 1)
+	}
+	if good != 1 || int(n) > len(data) {
+		return nil, DecryptionFailed
+	}
+	return data[:len(data)-int(n)], nil
+}
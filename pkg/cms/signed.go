@@ -0,0 +1,362 @@
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+var rsaSignatureAlgorithms = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA256: oidSHA256WithRSA,
+	crypto.SHA384: oidSHA384WithRSA,
+	crypto.SHA512: oidSHA512WithRSA,
+}
+
+var ecdsaSignatureAlgorithms = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA256: oidECDSAWithSHA256,
+	crypto.SHA384: oidECDSAWithSHA384,
+	crypto.SHA512: oidECDSAWithSHA512,
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     rawContent   `asn1:"optional,tag:0"`
+	CRLs             rawContent   `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"optional"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerialNumber
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        rawContent `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      rawContent `asn1:"optional,tag:1"`
+}
+
+// SignOptions configure Sign.
+type SignOptions struct {
+	// Hash is the message digest, defaulting to SHA-256.
+	Hash crypto.Hash
+	// Detached omits the content from the SignedData.
+	Detached bool
+	// Certificates are embedded alongside the signer certificate, typically
+	// its intermediates.
+	Certificates []*x509.Certificate
+	// SigningTime defaults to now.
+	SigningTime time.Time
+}
+
+// VerifyOptions configure SignedData.Verify.
+type VerifyOptions struct {
+	// Roots, when set, are used to validate each signer certificate, with
+	// the embedded certificates as intermediates.
+	Roots *x509.CertPool
+	// CurrentTime is the validation time, defaulting to now.
+	CurrentTime time.Time
+}
+
+// SignedData is a parsed CMS SignedData structure.
+type SignedData struct {
+	// Content is the encapsulated content, nil when detached.
+	Content []byte
+	// Certificates are the embedded certificates.
+	Certificates []*x509.Certificate
+
+	signerInfos []signerInfo
+}
+
+// Sign creates a DER encoded CMS SignedData over content, signed with key and
+// embedding cert.
+func Sign(content []byte, cert *x509.Certificate, key crypto.Signer, opts *SignOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &SignOptions{}
+	}
+	hash := opts.Hash
+	if hash == 0 {
+		hash = crypto.SHA256
+	}
+	digestOID, ok := digestAlgorithms[hash]
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest: %v", hash)
+	}
+	signatureOID, err := signatureAlgorithm(key, hash)
+	if err != nil {
+		return nil, err
+	}
+	signingTime := opts.SigningTime
+	if signingTime.IsZero() {
+		signingTime = time.Now()
+	}
+
+	contentDigest := digest(hash, content)
+	attrs, err := marshalAttributes(contentDigest, signingTime)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := key.Sign(rand.Reader, digest(hash, attrs), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates, err := marshalCertificates(append([]*x509.Certificate{cert}, opts.Certificates...))
+	if err != nil {
+		return nil, err
+	}
+	encap := encapsulatedContentInfo{EContentType: oidData}
+	if !opts.Detached {
+		octets, err := asn1.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		encap.EContent = explicit(0, octets)
+	}
+
+	return marshalContentInfo(oidSignedData, signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{algorithmIdentifier(digestOID)},
+		EncapContentInfo: encap,
+		Certificates:     certificates,
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                certificateID(cert),
+			DigestAlgorithm:    algorithmIdentifier(digestOID),
+			SignedAttrs:        rawContent{Raw: attrs},
+			SignatureAlgorithm: algorithmIdentifier(signatureOID),
+			Signature:          signature,
+		}},
+	})
+}
+
+// ParseSignedData parses a DER encoded CMS SignedData structure.
+func ParseSignedData(der []byte) (*SignedData, error) {
+	var sd signedData
+	err := parseContentInfo(der, oidSignedData, &sd)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := parseCertificates(sd.Certificates)
+	if err != nil {
+		return nil, err
+	}
+	parsed := &SignedData{
+		Certificates: certs,
+		signerInfos:  sd.SignerInfos,
+	}
+	if len(sd.EncapContentInfo.EContent.Bytes) > 0 {
+		octets, err := unwrapExplicit(sd.EncapContentInfo.EContent, 0)
+		if err != nil {
+			return nil, err
+		}
+		_, err = asn1.Unmarshal(octets, &parsed.Content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// Verify verifies every signer of the SignedData, returning the signer
+// certificates. Detached content is given as content; it must be nil for
+// attached signatures.
+func (sd *SignedData) Verify(content []byte, opts *VerifyOptions) ([]*x509.Certificate, error) {
+	if opts == nil {
+		opts = &VerifyOptions{}
+	}
+	if sd.Content != nil {
+		if content != nil && !bytes.Equal(content, sd.Content) {
+			return nil, errors.New("content does not match the attached content")
+		}
+		content = sd.Content
+	}
+	if content == nil {
+		return nil, errors.New("detached signature requires content")
+	}
+	if len(sd.signerInfos) == 0 {
+		return nil, errors.New("no signers")
+	}
+
+	signers := make([]*x509.Certificate, len(sd.signerInfos))
+	for i, signer := range sd.signerInfos {
+		cert, err := sd.verifySigner(signer, content)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Roots != nil {
+			err = sd.verifyCertificate(cert, opts)
+			if err != nil {
+				return nil, err
+			}
+		}
+		signers[i] = cert
+	}
+	return signers, nil
+}
+
+func (sd *SignedData) verifySigner(signer signerInfo, content []byte) (*x509.Certificate, error) {
+	var cert *x509.Certificate
+	for _, candidate := range sd.Certificates {
+		if signer.SID.matches(candidate) {
+			cert = candidate
+			break
+		}
+	}
+	if cert == nil {
+		return nil, errors.New("signer certificate not found")
+	}
+
+	hash, err := digestHash(signer.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	contentDigest := digest(hash, content)
+	signed := contentDigest
+	if len(signer.SignedAttrs.Raw) > 0 {
+		attrs := append([]byte{}, signer.SignedAttrs.Raw...)
+		attrs[0] = 0x31
+		err = checkAttributes(attrs, contentDigest)
+		if err != nil {
+			return nil, err
+		}
+		signed = digest(hash, attrs)
+	}
+
+	err = verifySignature(cert.PublicKey, hash, signed, signer.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+func (sd *SignedData) verifyCertificate(cert *x509.Certificate, opts *VerifyOptions) error {
+	intermediates := x509.NewCertPool()
+	for _, intermediate := range sd.Certificates {
+		intermediates.AddCert(intermediate)
+	}
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:         opts.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   opts.CurrentTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+func marshalAttributes(contentDigest []byte, signingTime time.Time) ([]byte, error) {
+	values := []struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{oidAttributeContentType, oidData},
+		{oidAttributeSigningTime, signingTime.UTC()},
+		{oidAttributeMessageDigest, contentDigest},
+	}
+	attrs := make([][]byte, len(values))
+	for i, value := range values {
+		encoded, err := asn1.Marshal(value.value)
+		if err != nil {
+			return nil, err
+		}
+		set, err := marshalSet([][]byte{encoded})
+		if err != nil {
+			return nil, err
+		}
+		attrs[i], err = asn1.Marshal(attribute{
+			Type:   value.oid,
+			Values: asn1.RawValue{FullBytes: set},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sortDER(attrs)
+	return marshalSet(attrs)
+}
+
+func checkAttributes(attrs []byte, contentDigest []byte) error {
+	var parsed []attribute
+	_, err := asn1.UnmarshalWithParams(attrs, &parsed, "set")
+	if err != nil {
+		return err
+	}
+	var contentType, messageDigest bool
+	for _, attr := range parsed {
+		switch {
+		case attr.Type.Equal(oidAttributeContentType):
+			var oid asn1.ObjectIdentifier
+			_, err := asn1.Unmarshal(attr.Values.Bytes, &oid)
+			if err != nil || !oid.Equal(oidData) {
+				return errors.New("unsupported signed content type")
+			}
+			contentType = true
+		case attr.Type.Equal(oidAttributeMessageDigest):
+			var value []byte
+			_, err := asn1.Unmarshal(attr.Values.Bytes, &value)
+			if err != nil || !bytes.Equal(value, contentDigest) {
+				return errors.New("message digest mismatch")
+			}
+			messageDigest = true
+		}
+	}
+	if !contentType || !messageDigest {
+		return errors.New("missing content type or message digest attribute")
+	}
+	return nil
+}
+
+func signatureAlgorithm(key crypto.Signer, hash crypto.Hash) (asn1.ObjectIdentifier, error) {
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		return rsaSignatureAlgorithms[hash], nil
+	case *ecdsa.PublicKey:
+		return ecdsaSignatureAlgorithms[hash], nil
+	default:
+		return nil, fmt.Errorf("unsupported signer key type: %v", reflect.TypeOf(key.Public()))
+	}
+}
+
+func verifySignature(key crypto.PublicKey, hash crypto.Hash, digest []byte, signature []byte) error {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), hash, digest, signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), digest, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported signer key type: %v", reflect.TypeOf(key))
+	}
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func sortDER(items [][]byte) {
+	sort.Slice(items, func(i, j int) bool {
+		return bytes.Compare(items[i], items[j]) < 0
+	})
+}
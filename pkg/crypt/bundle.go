@@ -0,0 +1,113 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// OrderChain deduplicates certs and orders them from the leaf to the root,
+// matching each certificate to its issuer by name, key identifier and
+// signature. Missing issuers are taken from pool. It fails if any of certs
+// is not part of the chain. The chain ends at a self-signed root, or at the
+// last certificate whose issuer was found.
+func OrderChain(certs []*x509.Certificate, pool []*x509.Certificate) ([]*x509.Certificate, error) {
+	certs = uniqueCertificates(certs)
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	leaf, err := findLeaf(certs)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []*x509.Certificate{leaf}
+	used := map[*x509.Certificate]bool{leaf: true}
+	for current := leaf; !IsSelfSigned(current); {
+		issuer := findIssuer(current, certs, used)
+		if issuer == nil {
+			issuer = findIssuer(current, pool, used)
+		}
+		if issuer == nil {
+			break
+		}
+		chain = append(chain, issuer)
+		used[issuer] = true
+		current = issuer
+	}
+
+	for _, cert := range certs {
+		if !used[cert] {
+			return nil, fmt.Errorf("broken chain: %s is not part of the chain of %s", cert.Subject, leaf.Subject)
+		}
+	}
+	return chain, nil
+}
+
+// IsSelfSigned reports whether cert is signed by its own key. Unlike
+// CheckSignatureFrom, it does not require cert to be a CA.
+func IsSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+func isIssuer(issuer *x509.Certificate, cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, issuer.RawSubject) {
+		return false
+	}
+	if len(cert.AuthorityKeyId) > 0 && len(issuer.SubjectKeyId) > 0 && !bytes.Equal(cert.AuthorityKeyId, issuer.SubjectKeyId) {
+		return false
+	}
+	return cert.CheckSignatureFrom(issuer) == nil
+}
+
+func findIssuer(cert *x509.Certificate, candidates []*x509.Certificate, used map[*x509.Certificate]bool) *x509.Certificate {
+	for _, candidate := range candidates {
+		if !used[candidate] && isIssuer(candidate, cert) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// findLeaf returns the one certificate that issues none of the others.
+func findLeaf(certs []*x509.Certificate) (*x509.Certificate, error) {
+	var leaves []*x509.Certificate
+	for _, cert := range certs {
+		issuesOther := false
+		for _, other := range certs {
+			if other != cert && isIssuer(cert, other) {
+				issuesOther = true
+				break
+			}
+		}
+		if !issuesOther {
+			leaves = append(leaves, cert)
+		}
+	}
+	switch len(leaves) {
+	case 0:
+		return nil, errors.New("broken chain: no leaf certificate found")
+	case 1:
+		return leaves[0], nil
+	default:
+		subjects := make([]string, len(leaves))
+		for i, leaf := range leaves {
+			subjects[i] = leaf.Subject.String()
+		}
+		return nil, fmt.Errorf("broken chain: found %d leaf certificates: %s", len(leaves), strings.Join(subjects, "; "))
+	}
+}
+
+func uniqueCertificates(certs []*x509.Certificate) []*x509.Certificate {
+	var unique []*x509.Certificate
+	seen := make(map[string]bool)
+	for _, cert := range certs {
+		if !seen[string(cert.Raw)] {
+			seen[string(cert.Raw)] = true
+			unique = append(unique, cert)
+		}
+	}
+	return unique
+}
@@ -1,113 +1,118 @@
 package crypt
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/x509"
+	"errors"
 	"fmt"
-	"github.com/credding/crypt/pkg/encoding"
-	"github.com/credding/crypt/pkg/flags"
-	"github.com/spf13/cobra"
 	"math"
 	"math/big"
-	"os"
-	"reflect"
 	"time"
 )
 
-var (
-	certParent     = flags.FileRead()
-	certSigningKey = flags.FileRead()
-	certExpiry     flags.Time
-	certIsCA       bool
-)
+// DefaultCertificateValidity is the validity of certificates issued without
+// an explicit expiry.
+const DefaultCertificateValidity = 365 * 24 * time.Hour
 
-var certCommand = &cobra.Command{
-	Use:   "cert",
-	Short: "Generate a certificate given a CSR on stdin",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		csrPem, err := encoding.DecodePEM(os.Stdin)
-		if err != nil {
-			return err
-		}
-		csr, ok := csrPem[0].(*x509.CertificateRequest)
-		if !ok {
-			return fmt.Errorf("expected a CSR, got %v", reflect.TypeOf(csrPem[0]))
-		}
-		template, err := certificateTemplate(csr)
-		if err != nil {
-			return nil
-		}
-		parent, err := certificateParent(template)
-		if err != nil {
-			return err
-		}
-		keyPem, err := encoding.DecodePEM(certSigningKey.File())
-		if err != nil {
-			return err
-		}
-		certRaw, err := x509.CreateCertificate(rand.Reader, parent, template, csr.PublicKey, keyPem[0])
-		if err != nil {
-			return err
-		}
-		cert, err := x509.ParseCertificate(certRaw)
-		if err != nil {
-			return err
-		}
-		return encoding.EncodePEM(os.Stdout, cert)
-	},
+// Issuer signs certificates. A nil Certificate issues self-signed
+// certificates.
+type Issuer struct {
+	Certificate *x509.Certificate
+	Key         crypto.Signer
 }
 
-func init() {
-	options := certCommand.Flags()
-	options.SortFlags = false
-	options.VarP(certParent, "parent", "p", "Parent certificate (default self-signed)")
-	options.VarP(certSigningKey, "key", "k", "Certificate signing key")
-	options.VarP(&certExpiry, "expires", "e", "Certificate expiry (default \"8760h\")")
-	options.BoolVar(&certIsCA, "ca", false, "Generate a CA certificate")
-
-	_ = certCommand.MarkFlagRequired("key")
+// CertificateOptions configure IssueCertificate.
+type CertificateOptions struct {
+	// NotBefore defaults to now.
+	NotBefore time.Time
+	// NotAfter defaults to DefaultCertificateValidity after NotBefore.
+	NotAfter time.Time
+	IsCA     bool
+	Extensions
+	// Policy defaults to DefaultIssuancePolicy.
+	Policy *IssuancePolicy
 }
 
-func certificateTemplate(csr *x509.CertificateRequest) (*x509.Certificate, error) {
-	serialNumber, err := certificateSerialNumber()
+// IssueCertificate issues a certificate for a certificate signing request,
+// after checking its signature and the issuance policy.
+func IssueCertificate(csr *x509.CertificateRequest, issuer *Issuer, opts *CertificateOptions) (*x509.Certificate, error) {
+	if issuer == nil || issuer.Key == nil {
+		return nil, errors.New("issuer key not provided")
+	}
+	if opts == nil {
+		opts = &CertificateOptions{}
+	}
+	err := csr.CheckSignature()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSR signature: %w", err)
+	}
+	template, err := CertificateTemplate(csr, opts)
 	if err != nil {
 		return nil, err
 	}
+	err = issuancePolicy(opts).Check(csr, template.NotBefore, template.NotAfter)
+	if err != nil {
+		return nil, err
+	}
+	parent := issuer.Certificate
+	if parent == nil {
+		parent = template
+	}
 
-	return &x509.Certificate{
-		SignatureAlgorithm: csr.SignatureAlgorithm,
-		SerialNumber:       serialNumber,
-		Subject:            csr.Subject,
-		NotBefore:          time.Now(),
-		NotAfter:           certificateExpiry(),
-		IsCA:               certIsCA,
-		ExtraExtensions:    csr.Extensions,
-	}, nil
+	certRaw, err := x509.CreateCertificate(rand.Reader, template, parent, csr.PublicKey, issuer.Key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(certRaw)
 }
 
-func certificateParent(template *x509.Certificate) (*x509.Certificate, error) {
-	if certParent.File() == nil {
-		return template, nil
-	}
-	parentPem, err := encoding.DecodePEM(certParent.File())
+// CertificateTemplate returns the template IssueCertificate signs for a
+// certificate signing request. Only the requested extensions the policy
+// honors are copied.
+func CertificateTemplate(csr *x509.CertificateRequest, opts *CertificateOptions) (*x509.Certificate, error) {
+	serialNumber, err := SerialNumber()
 	if err != nil {
 		return nil, err
 	}
-	parent, ok := parentPem[0].(*x509.Certificate)
-	if !ok {
-		return nil, fmt.Errorf("expected parent to be a certificate, got %v", reflect.TypeOf(parentPem[0]))
+	notBefore := opts.NotBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now()
+	}
+	notAfter := opts.NotAfter
+	if notAfter.IsZero() {
+		notAfter = notBefore.Add(DefaultCertificateValidity)
+	}
+
+	honored, _ := issuancePolicy(opts).FilterExtensions(csr)
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               csr.Subject,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  opts.IsCA,
+		ExtraExtensions:       honored,
+	}
+	if opts.IsCA {
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+	}
+	err = opts.Extensions.apply(template)
+	if err != nil {
+		return nil, err
 	}
-	return parent, nil
+	return template, nil
 }
 
-func certificateExpiry() time.Time {
-	if certExpiry > 0 {
-		return time.Unix(int64(certExpiry), 0)
-	} else {
-		return time.Now().Add(365 * 24 * time.Hour)
+func issuancePolicy(opts *CertificateOptions) *IssuancePolicy {
+	if opts.Policy == nil {
+		return DefaultIssuancePolicy
 	}
+	return opts.Policy
 }
 
-func certificateSerialNumber() (*big.Int, error) {
+// SerialNumber returns a random certificate serial number.
+func SerialNumber() (*big.Int, error) {
 	return rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
 }
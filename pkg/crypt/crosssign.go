@@ -0,0 +1,59 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+)
+
+// CrossSign issues a certificate with the subject, public key and subject
+// key identifier of cert under a different issuer. The validity defaults to
+// that of cert, ending no later than the issuer's. Distribution point and
+// authority information access extensions describe the original issuer, so
+// they are not copied.
+func CrossSign(cert *x509.Certificate, issuer *Issuer, opts *CertificateOptions) (*x509.Certificate, error) {
+	if issuer == nil || issuer.Certificate == nil || issuer.Key == nil {
+		return nil, errors.New("issuer certificate and key not provided")
+	}
+	if !cert.IsCA {
+		return nil, errors.New("only CA certificates can be cross-signed")
+	}
+	if opts == nil {
+		opts = &CertificateOptions{}
+	}
+	validity := *opts
+	if validity.NotBefore.IsZero() {
+		validity.NotBefore = cert.NotBefore
+	}
+	if validity.NotAfter.IsZero() {
+		validity.NotAfter = cert.NotAfter
+		if issuer.Certificate.NotAfter.Before(validity.NotAfter) {
+			validity.NotAfter = issuer.Certificate.NotAfter
+		}
+	}
+	template, err := RenewalTemplate(cert, &validity)
+	if err != nil {
+		return nil, err
+	}
+	template.CRLDistributionPoints = nil
+	template.OCSPServer = nil
+	template.IssuingCertificateURL = nil
+
+	certRaw, err := x509.CreateCertificate(rand.Reader, template, issuer.Certificate, cert.PublicKey, issuer.Key)
+	if err != nil {
+		return nil, err
+	}
+	crossSigned, err := x509.ParseCertificate(certRaw)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(crossSigned.RawSubject, cert.RawSubject) {
+		return nil, errors.New("cross-signed certificate subject differs from the original")
+	}
+	err = crossSigned.CheckSignatureFrom(issuer.Certificate)
+	if err != nil {
+		return nil, err
+	}
+	return crossSigned, nil
+}
@@ -1,26 +1,3 @@
+// Package crypt generates keys, certificate signing requests and
+// certificates, and derives public keys from private key material.
 package crypt
-
-import (
-	"github.com/spf13/cobra"
-)
-
-var rootCommand = &cobra.Command{
-	Use:   "crypt",
-	Short: "Simple cryptography toolset",
-}
-
-func Execute() {
-	_ = rootCommand.Execute()
-}
-
-func init() {
-	cobra.EnableCommandSorting = false
-	rootCommand.AddCommand(
-		rsaCommand,
-		ecdsaCommand,
-		csrCommand,
-		certCommand,
-		publicCommand,
-		randCommand,
-	)
-}
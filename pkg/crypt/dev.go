@@ -0,0 +1,191 @@
+package crypt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"github.com/credding/crypt/pkg/encoding"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+const (
+	devCACertificateFile = "ca.pem"
+	devCAKeyFile         = "ca-key.pem"
+	devCAValidity        = 10 * 365 * 24 * time.Hour
+)
+
+// DefaultDevCertificateValidity is the validity of development certificates,
+// the longest some clients accept.
+const DefaultDevCertificateValidity = 825 * 24 * time.Hour
+
+// DevCADir returns the default directory of the development CA, under the
+// user's config directory.
+func DevCADir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "crypt", "dev-ca"), nil
+}
+
+// LoadDevCA loads the development CA in dir, creating it if it does not
+// exist. The second result reports whether it was created.
+func LoadDevCA(dir string) (*Issuer, bool, error) {
+	certPath := filepath.Join(dir, devCACertificateFile)
+	keyPath := filepath.Join(dir, devCAKeyFile)
+	_, err := os.Stat(certPath)
+	if os.IsNotExist(err) {
+		issuer, err := createDevCA(certPath, keyPath)
+		return issuer, true, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	cert, err := decodePEMFile(certPath)
+	if err != nil {
+		return nil, false, err
+	}
+	certificate, ok := cert.(*x509.Certificate)
+	if !ok {
+		return nil, false, fmt.Errorf("%s: expected a certificate, got %v", certPath, reflect.TypeOf(cert))
+	}
+	key, err := decodePEMFile(keyPath)
+	if err != nil {
+		return nil, false, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, false, fmt.Errorf("%s: expected a private key, got %v", keyPath, reflect.TypeOf(key))
+	}
+	return &Issuer{Certificate: certificate, Key: signer}, false, nil
+}
+
+// NewDevCA creates a development CA without saving it.
+func NewDevCA() (*Issuer, error) {
+	key, err := GenerateECDSAKey("P-256")
+	if err != nil {
+		return nil, err
+	}
+	serialNumber, err := SerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	notBefore := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: "crypt development CA " + hostname, Organization: []string{"crypt development CA"}},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(devCAValidity),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+	certRaw, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(certRaw)
+	if err != nil {
+		return nil, err
+	}
+	return &Issuer{Certificate: cert, Key: key}, nil
+}
+
+// DevCACertificatePath returns the path of the certificate of the
+// development CA in dir, for adding to trust stores.
+func DevCACertificatePath(dir string) string {
+	return filepath.Join(dir, devCACertificateFile)
+}
+
+// IssueDevCertificate issues a TLS server and client certificate for the
+// given DNS names and IP addresses, with a new key.
+func IssueDevCertificate(issuer *Issuer, names []string, notAfter time.Time) (crypto.Signer, *x509.Certificate, error) {
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("no names provided")
+	}
+	key, err := GenerateECDSAKey("P-256")
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := SerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	notBefore := time.Now()
+	if notAfter.IsZero() {
+		notAfter = notBefore.Add(DefaultDevCertificateValidity)
+	}
+	if notAfter.After(issuer.Certificate.NotAfter) {
+		notAfter = issuer.Certificate.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: names[0], Organization: []string{"crypt development certificate"}},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, name := range names {
+		ip := net.ParseIP(name)
+		if ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	certRaw, err := x509.CreateCertificate(rand.Reader, template, issuer.Certificate, key.Public(), issuer.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(certRaw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, cert, nil
+}
+
+func createDevCA(certPath string, keyPath string) (*Issuer, error) {
+	err := os.MkdirAll(filepath.Dir(certPath), 0700)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := NewDevCA()
+	if err != nil {
+		return nil, err
+	}
+	err = encoding.WritePEMFile(keyPath, issuer.Key, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = encoding.WritePEMFile(certPath, issuer.Certificate, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return issuer, nil
+}
+
+func decodePEMFile(path string) (interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	chain, err := encoding.DecodePEM(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return chain[0], nil
+}
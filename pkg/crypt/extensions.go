@@ -0,0 +1,111 @@
+package crypt
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// OIDTLSFeature is the TLS Feature extension of RFC 7633.
+var OIDTLSFeature = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+// statusRequest is the TLS Feature requiring OCSP stapling.
+const statusRequest = 5
+
+// NameConstraints are the names a CA certificate may issue for.
+type NameConstraints struct {
+	Critical                bool
+	PermittedDNSDomains     []string
+	ExcludedDNSDomains      []string
+	PermittedIPRanges       []*net.IPNet
+	ExcludedIPRanges        []*net.IPNet
+	PermittedEmailAddresses []string
+	ExcludedEmailAddresses  []string
+	PermittedURIDomains     []string
+	ExcludedURIDomains      []string
+}
+
+// Extensions are the optional extensions of issued certificates.
+type Extensions struct {
+	NameConstraints NameConstraints
+	Policies        []asn1.ObjectIdentifier
+	// CRLDistributionPoints are CRL URLs.
+	CRLDistributionPoints []string
+	// OCSPServers and IssuingCertificateURLs make up the authority
+	// information access extension.
+	OCSPServers            []string
+	IssuingCertificateURLs []string
+	// MustStaple adds the TLS Feature extension requiring OCSP stapling.
+	MustStaple bool
+}
+
+// ParseOID parses a dotted object identifier such as 2.23.140.1.2.1.
+func ParseOID(text string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(text, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid object identifier: %s", text)
+	}
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid object identifier: %s", text)
+		}
+		oid[i] = value
+	}
+	return oid, nil
+}
+
+// HasMustStaple reports whether cert requires OCSP stapling.
+func HasMustStaple(cert *x509.Certificate) bool {
+	for _, extension := range cert.Extensions {
+		if !extension.Id.Equal(OIDTLSFeature) {
+			continue
+		}
+		var features []int
+		_, err := asn1.Unmarshal(extension.Value, &features)
+		if err != nil {
+			return false
+		}
+		for _, feature := range features {
+			if feature == statusRequest {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *Extensions) apply(template *x509.Certificate) error {
+	constraints := e.NameConstraints
+	template.PermittedDNSDomainsCritical = constraints.Critical
+	template.PermittedDNSDomains = constraints.PermittedDNSDomains
+	template.ExcludedDNSDomains = constraints.ExcludedDNSDomains
+	template.PermittedIPRanges = constraints.PermittedIPRanges
+	template.ExcludedIPRanges = constraints.ExcludedIPRanges
+	template.PermittedEmailAddresses = constraints.PermittedEmailAddresses
+	template.ExcludedEmailAddresses = constraints.ExcludedEmailAddresses
+	template.PermittedURIDomains = constraints.PermittedURIDomains
+	template.ExcludedURIDomains = constraints.ExcludedURIDomains
+	template.PolicyIdentifiers = e.Policies
+	template.CRLDistributionPoints = e.CRLDistributionPoints
+	template.OCSPServer = e.OCSPServers
+	template.IssuingCertificateURL = e.IssuingCertificateURLs
+
+	if e.MustStaple {
+		value, err := asn1.Marshal([]int{statusRequest})
+		if err != nil {
+			return err
+		}
+		extensions := template.ExtraExtensions
+		template.ExtraExtensions = append(extensions[:len(extensions):len(extensions)], pkix.Extension{
+			Id:    OIDTLSFeature,
+			Value: value,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package crypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+	"hash"
+	"io"
+	"sort"
+)
+
+var hashes = map[string]func() hash.Hash{
+	"sha224":      sha256.New224,
+	"sha256":      sha256.New,
+	"sha384":      sha512.New384,
+	"sha512":      sha512.New,
+	"sha512-256":  sha512.New512_256,
+	"sha3-224":    sha3.New224,
+	"sha3-256":    sha3.New256,
+	"sha3-384":    sha3.New384,
+	"sha3-512":    sha3.New512,
+	"blake2b-256": blake2bHash(blake2b.Size256),
+	"blake2b-384": blake2bHash(blake2b.Size384),
+	"blake2b-512": blake2bHash(blake2b.Size),
+}
+
+// HashNames returns the names NewHash accepts.
+func HashNames() []string {
+	names := make([]string, 0, len(hashes))
+	for name := range hashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewHash returns the named SHA-2, SHA-3 or BLAKE2b hash, such as sha256,
+// sha3-256, or blake2b-512.
+func NewHash(name string) (hash.Hash, error) {
+	newHash, err := hashFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	return newHash(), nil
+}
+
+// NewHMAC returns an HMAC of the named hash keyed with key.
+func NewHMAC(name string, key []byte) (hash.Hash, error) {
+	newHash, err := hashFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("hmac key is empty")
+	}
+	return hmac.New(newHash, key), nil
+}
+
+// DigestReader returns the digest of everything read from in.
+func DigestReader(h hash.Hash, in io.Reader) ([]byte, error) {
+	_, err := io.Copy(h, in)
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func hashFunc(name string) (func() hash.Hash, error) {
+	newHash, ok := hashes[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash: %s", name)
+	}
+	return newHash, nil
+}
+
+func blake2bHash(size int) func() hash.Hash {
+	return func() hash.Hash {
+		h, err := blake2b.New(size, nil)
+		if err != nil {
+			panic(err)
+		}
+		return h
+	}
+}
@@ -0,0 +1,104 @@
+package crypt
+
+import (
+	"errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"io"
+)
+
+// ScryptParams are the scrypt cost parameters.
+type ScryptParams struct {
+	N int
+	R int
+	P int
+}
+
+// Argon2Params are the Argon2id cost parameters. Memory is in KiB.
+type Argon2Params struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+var (
+	DefaultScryptParams = ScryptParams{N: 32768, R: 8, P: 1}
+	DefaultArgon2Params = Argon2Params{Time: 3, Memory: 64 * 1024, Threads: 4}
+)
+
+var invalidKeyLength = errors.New("derived key length must be positive")
+
+// HKDF derives length bytes from secret with HKDF extract and expand.
+func HKDF(hashName string, secret []byte, salt []byte, info []byte, length int) ([]byte, error) {
+	newHash, err := hashFunc(hashName)
+	if err != nil {
+		return nil, err
+	}
+	return readKey(hkdf.New(newHash, secret, salt, info), length)
+}
+
+// HKDFExtract returns the HKDF pseudorandom key for secret and salt.
+func HKDFExtract(hashName string, secret []byte, salt []byte) ([]byte, error) {
+	newHash, err := hashFunc(hashName)
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.Extract(newHash, secret, salt), nil
+}
+
+// HKDFExpand expands a pseudorandom key into length bytes.
+func HKDFExpand(hashName string, prk []byte, info []byte, length int) ([]byte, error) {
+	newHash, err := hashFunc(hashName)
+	if err != nil {
+		return nil, err
+	}
+	return readKey(hkdf.Expand(newHash, prk, info), length)
+}
+
+// PBKDF2 derives length bytes from password with PBKDF2 over the named hash.
+func PBKDF2(hashName string, password []byte, salt []byte, iterations int, length int) ([]byte, error) {
+	newHash, err := hashFunc(hashName)
+	if err != nil {
+		return nil, err
+	}
+	if iterations < 1 {
+		return nil, errors.New("pbkdf2 iterations must be positive")
+	}
+	if length < 1 {
+		return nil, invalidKeyLength
+	}
+	return pbkdf2.Key(password, salt, iterations, length, newHash), nil
+}
+
+// Scrypt derives length bytes from password with scrypt.
+func Scrypt(password []byte, salt []byte, params ScryptParams, length int) ([]byte, error) {
+	if length < 1 {
+		return nil, invalidKeyLength
+	}
+	return scrypt.Key(password, salt, params.N, params.R, params.P, length)
+}
+
+// Argon2id derives length bytes from password with Argon2id.
+func Argon2id(password []byte, salt []byte, params Argon2Params, length int) ([]byte, error) {
+	if length < 1 {
+		return nil, invalidKeyLength
+	}
+	if params.Time < 1 || params.Threads < 1 || params.Memory < 8*uint32(params.Threads) {
+		return nil, errors.New("invalid argon2id parameters")
+	}
+	return argon2.IDKey(password, salt, params.Time, params.Memory, params.Threads, uint32(length)), nil
+}
+
+func readKey(reader io.Reader, length int) ([]byte, error) {
+	if length < 1 {
+		return nil, invalidKeyLength
+	}
+	key := make([]byte, length)
+	_, err := io.ReadFull(reader, key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,70 @@
+package crypt
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"regexp"
+)
+
+var curvePattern = regexp.MustCompile("(?i)p-?(\\d+)")
+
+// GenerateRSAKey generates an RSA key of the given size in bits.
+func GenerateRSAKey(bits int) (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, bits)
+}
+
+// GenerateECDSAKey generates an ECDSA key on the named curve.
+func GenerateECDSAKey(curveName string) (*ecdsa.PrivateKey, error) {
+	curve, err := ParseCurve(curveName)
+	if err != nil {
+		return nil, err
+	}
+	return ecdsa.GenerateKey(curve, rand.Reader)
+}
+
+// GenerateEd25519Key generates an Ed25519 signing key.
+func GenerateEd25519Key() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	return key, err
+}
+
+// GenerateX25519Key generates an X25519 key agreement key.
+func GenerateX25519Key() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// ParseCurve returns the NIST curve named as P-256, p256, or similar.
+func ParseCurve(name string) (elliptic.Curve, error) {
+	nameMatch := curvePattern.FindStringSubmatch(name)
+	if nameMatch == nil {
+		return nil, fmt.Errorf("unsupported curve: %s", name)
+	}
+	switch nameMatch[1] {
+	case "256":
+		return elliptic.P256(), nil
+	case "384":
+		return elliptic.P384(), nil
+	case "521":
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("unsupported curve: %s", name)
+	}
+}
+
+// RandomBytes returns n cryptographically random bytes.
+func RandomBytes(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("invalid byte count: %d", n)
+	}
+	data := make([]byte, n)
+	_, err := rand.Read(data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
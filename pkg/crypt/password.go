@@ -0,0 +1,282 @@
+package crypt
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// PasswordHashes are the algorithms HashPassword accepts.
+var PasswordHashes = []string{"argon2id", "scrypt", "bcrypt", "pbkdf2-sha256"}
+
+// PasswordParams are the cost parameters for HashPassword. When verifying,
+// they are the policy stored hashes are checked against.
+type PasswordParams struct {
+	Argon2           Argon2Params
+	Scrypt           ScryptParams
+	BcryptCost       int
+	PBKDF2Iterations int
+}
+
+// DefaultPasswordParams are the costs used when none are given.
+var DefaultPasswordParams = PasswordParams{
+	Argon2:           DefaultArgon2Params,
+	Scrypt:           DefaultScryptParams,
+	BcryptCost:       12,
+	PBKDF2Iterations: 600000,
+}
+
+// PasswordMismatch is returned when a password does not match a stored hash.
+var PasswordMismatch = errors.New("password does not match")
+
+const (
+	passwordSaltSize = 16
+	passwordKeySize  = 32
+	bcryptMaxLength  = 72
+)
+
+// Limits on the parameters of stored hashes, so verifying a hostile or
+// corrupt hash cannot exhaust memory or CPU.
+const (
+	maxArgon2Memory     = 2 << 20 // KiB
+	maxArgon2Time       = 100
+	maxScryptMemory     = 1 << 30 // bytes, 128 * N * r
+	maxScryptLogN       = 24
+	maxScryptR          = 64
+	maxScryptP          = 64
+	maxPBKDF2Iterations = 10000000
+	maxPasswordKeySize  = 128
+)
+
+var phcEncoding = base64.RawStdEncoding
+
+// HashPassword hashes password with the named algorithm, returning a PHC
+// format string. bcrypt hashes use the $2a$ modular crypt format.
+func HashPassword(alg string, password []byte, params PasswordParams) (string, error) {
+	if alg == "bcrypt" {
+		if len(password) > bcryptMaxLength {
+			return "", fmt.Errorf("bcrypt passwords are limited to %d bytes", bcryptMaxLength)
+		}
+		if params.BcryptCost < bcrypt.MinCost || params.BcryptCost > bcrypt.MaxCost {
+			return "", fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, params.BcryptCost)
+		}
+		hash, err := bcrypt.GenerateFromPassword(password, params.BcryptCost)
+		return string(hash), err
+	}
+	salt, err := RandomBytes(passwordSaltSize)
+	if err != nil {
+		return "", err
+	}
+	var hash *passwordHash
+	switch alg {
+	case "argon2id":
+		hash = &passwordHash{alg: alg, argon2: params.Argon2, salt: salt}
+	case "scrypt":
+		hash = &passwordHash{alg: alg, scrypt: params.Scrypt, salt: salt}
+	case "pbkdf2-sha256":
+		hash = &passwordHash{alg: alg, iterations: params.PBKDF2Iterations, salt: salt}
+	default:
+		return "", fmt.Errorf("unsupported password hash: %s", alg)
+	}
+	err = hash.checkParams()
+	if err != nil {
+		return "", err
+	}
+	hash.key, err = hash.derive(password, passwordKeySize)
+	if err != nil {
+		return "", err
+	}
+	return hash.String(), nil
+}
+
+// VerifyPassword checks password against a stored hash. It returns
+// PasswordMismatch if the password is wrong. Hashes with costs above the
+// supported limits are rejected before deriving.
+func VerifyPassword(encoded string, password []byte) error {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), password)
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return PasswordMismatch
+		}
+		return err
+	}
+	hash, err := parsePasswordHash(encoded)
+	if err != nil {
+		return err
+	}
+	key, err := hash.derive(password, len(hash.key))
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(key, hash.key) != 1 {
+		return PasswordMismatch
+	}
+	return nil
+}
+
+// PasswordPolicyViolations lists the ways a stored hash is weaker than
+// policy. It is empty when the hash meets policy.
+func PasswordPolicyViolations(encoded string, policy PasswordParams) ([]string, error) {
+	var violations []string
+	below := func(name string, actual int, minimum int) {
+		if actual < minimum {
+			violations = append(violations, fmt.Sprintf("%s %d is below policy %d", name, actual, minimum))
+		}
+	}
+	if isBcrypt(encoded) {
+		cost, err := bcrypt.Cost([]byte(encoded))
+		if err != nil {
+			return nil, err
+		}
+		below("bcrypt cost", cost, policy.BcryptCost)
+		return violations, nil
+	}
+	hash, err := parsePasswordHash(encoded)
+	if err != nil {
+		return nil, err
+	}
+	switch hash.alg {
+	case "argon2id":
+		below("argon2id memory", int(hash.argon2.Memory), int(policy.Argon2.Memory))
+		below("argon2id time", int(hash.argon2.Time), int(policy.Argon2.Time))
+	case "scrypt":
+		below("scrypt N", hash.scrypt.N, policy.Scrypt.N)
+		below("scrypt r", hash.scrypt.R, policy.Scrypt.R)
+	case "pbkdf2-sha256":
+		below("pbkdf2 iterations", hash.iterations, policy.PBKDF2Iterations)
+	}
+	below("salt length", len(hash.salt), passwordSaltSize)
+	return violations, nil
+}
+
+type passwordHash struct {
+	alg        string
+	argon2     Argon2Params
+	scrypt     ScryptParams
+	iterations int
+	salt       []byte
+	key        []byte
+}
+
+// checkParams rejects parameters outside the supported limits.
+func (h *passwordHash) checkParams() error {
+	switch h.alg {
+	case "argon2id":
+		a := h.argon2
+		if a.Memory < 1 || a.Memory > maxArgon2Memory || a.Time < 1 || a.Time > maxArgon2Time || a.Threads < 1 {
+			return fmt.Errorf("argon2id parameters out of range: m=%d,t=%d,p=%d", a.Memory, a.Time, a.Threads)
+		}
+	case "scrypt":
+		p := h.scrypt
+		if p.N < 2 || p.N > 1<<maxScryptLogN || p.R < 1 || p.R > maxScryptR || p.P < 1 || p.P > maxScryptP || p.N > maxScryptMemory/128/p.R {
+			return fmt.Errorf("scrypt parameters out of range: N=%d,r=%d,p=%d", p.N, p.R, p.P)
+		}
+	case "pbkdf2-sha256":
+		if h.iterations < 1 || h.iterations > maxPBKDF2Iterations {
+			return fmt.Errorf("pbkdf2 iterations out of range: %d", h.iterations)
+		}
+	}
+	return nil
+}
+
+func (h *passwordHash) derive(password []byte, length int) ([]byte, error) {
+	switch h.alg {
+	case "argon2id":
+		return Argon2id(password, h.salt, h.argon2, length)
+	case "scrypt":
+		return Scrypt(password, h.salt, h.scrypt, length)
+	default:
+		return PBKDF2("sha256", password, h.salt, h.iterations, length)
+	}
+}
+
+func (h *passwordHash) String() string {
+	var params string
+	switch h.alg {
+	case "argon2id":
+		params = fmt.Sprintf("v=19$m=%d,t=%d,p=%d", h.argon2.Memory, h.argon2.Time, h.argon2.Threads)
+	case "scrypt":
+		params = fmt.Sprintf("ln=%d,r=%d,p=%d", bits.Len(uint(h.scrypt.N))-1, h.scrypt.R, h.scrypt.P)
+	default:
+		params = fmt.Sprintf("i=%d", h.iterations)
+	}
+	return fmt.Sprintf("$%s$%s$%s$%s", h.alg, params, phcEncoding.EncodeToString(h.salt), phcEncoding.EncodeToString(h.key))
+}
+
+func parsePasswordHash(encoded string) (*passwordHash, error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) < 5 || fields[0] != "" {
+		return nil, errors.New("invalid password hash")
+	}
+	hash := &passwordHash{alg: fields[1]}
+	if hash.alg == "argon2id" {
+		if fields[2] != "v=19" {
+			return nil, fmt.Errorf("unsupported argon2id version: %s", fields[2])
+		}
+		fields = append(fields[:2], fields[3:]...)
+	}
+	if len(fields) != 5 {
+		return nil, errors.New("invalid password hash")
+	}
+	params, err := parsePHCParams(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	switch hash.alg {
+	case "argon2id":
+		if params["m"] > maxArgon2Memory || params["t"] > maxArgon2Time || params["p"] > 255 {
+			return nil, fmt.Errorf("argon2id parameters out of range: %s", fields[2])
+		}
+		hash.argon2 = Argon2Params{Memory: uint32(params["m"]), Time: uint32(params["t"]), Threads: uint8(params["p"])}
+	case "scrypt":
+		if params["ln"] < 1 || params["ln"] > maxScryptLogN {
+			return nil, fmt.Errorf("scrypt parameters out of range: %s", fields[2])
+		}
+		hash.scrypt = ScryptParams{N: 1 << uint(params["ln"]), R: params["r"], P: params["p"]}
+	case "pbkdf2-sha256":
+		hash.iterations = params["i"]
+	default:
+		return nil, fmt.Errorf("unsupported password hash: %s", hash.alg)
+	}
+	err = hash.checkParams()
+	if err != nil {
+		return nil, err
+	}
+	hash.salt, err = phcEncoding.DecodeString(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	hash.key, err = phcEncoding.DecodeString(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	if len(hash.key) == 0 || len(hash.key) > maxPasswordKeySize {
+		return nil, errors.New("invalid password hash")
+	}
+	return hash, nil
+}
+
+func parsePHCParams(text string) (map[string]int, error) {
+	params := make(map[string]int)
+	for _, param := range strings.Split(text, ",") {
+		pair := strings.SplitN(param, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("invalid password hash parameter: %s", param)
+		}
+		value, err := strconv.Atoi(pair[1])
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid password hash parameter: %s", param)
+		}
+		params[pair[0]] = value
+	}
+	return params, nil
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
@@ -0,0 +1,332 @@
+package crypt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+
+// GeneralName tags of the subject alternative names a policy covers.
+const (
+	sanEmail = 1
+	sanDNS   = 2
+	sanURI   = 6
+	sanIP    = 7
+)
+
+// Requested extensions that can be honored by name. Basic constraints and
+// key identifiers always come from the issuer, so they can't be honored.
+var extensionNames = map[string]asn1.ObjectIdentifier{
+	"subjectAltName":      oidSubjectAltName,
+	"keyUsage":            {2, 5, 29, 15},
+	"extKeyUsage":         {2, 5, 29, 37},
+	"certificatePolicies": {2, 5, 29, 32},
+	"tlsFeature":          OIDTLSFeature,
+}
+
+var unhonorableExtensions = []asn1.ObjectIdentifier{
+	{2, 5, 29, 19}, // basic constraints
+	{2, 5, 29, 14}, // subject key identifier
+	{2, 5, 29, 35}, // authority key identifier
+	{2, 5, 29, 30}, // name constraints
+}
+
+// IssuancePolicy restricts the certificates issued for certificate signing
+// requests. A nil name list allows any name of that type, and an empty one
+// allows none. Subject alternative names of other types are never allowed.
+type IssuancePolicy struct {
+	// AllowedDNSNames are DNS names, or wildcard patterns such as
+	// *.example.com matching a single label. They also restrict the subject
+	// common name.
+	AllowedDNSNames []string
+	AllowedIPRanges []*net.IPNet
+	// AllowedEmailAddresses are addresses, or domains such as @example.com.
+	AllowedEmailAddresses []string
+	// AllowedURIPrefixes are prefixes such as spiffe://example.org/.
+	AllowedURIPrefixes []string
+	// MaxValidity is unlimited if zero.
+	MaxValidity time.Duration
+	// KeyTypes are rsa-<minimum bits>, ecdsa-p256, ecdsa-p384, ecdsa-p521,
+	// or ed25519. Any key type is allowed if empty.
+	KeyTypes []string
+	// HonorExtensions are the requested extensions copied into the
+	// certificate, by name or dotted OID. Others are stripped.
+	HonorExtensions []asn1.ObjectIdentifier
+}
+
+// DefaultIssuancePolicy honors only the subject alternative name extension.
+var DefaultIssuancePolicy = &IssuancePolicy{
+	HonorExtensions: []asn1.ObjectIdentifier{oidSubjectAltName},
+}
+
+type issuancePolicyJSON struct {
+	AllowedDNSNames       []string `json:"allowedDNSNames"`
+	AllowedIPRanges       []string `json:"allowedIPRanges"`
+	AllowedEmailAddresses []string `json:"allowedEmailAddresses"`
+	AllowedURIPrefixes    []string `json:"allowedURIPrefixes"`
+	MaxValidity           string   `json:"maxValidity"`
+	KeyTypes              []string `json:"keyTypes"`
+	HonorExtensions       []string `json:"honorExtensions"`
+}
+
+// ParseIssuancePolicy parses a JSON issuance policy. Omitting
+// honorExtensions honors only subjectAltName.
+func ParseIssuancePolicy(data []byte) (*IssuancePolicy, error) {
+	var raw issuancePolicyJSON
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+	policy := &IssuancePolicy{
+		AllowedDNSNames:       raw.AllowedDNSNames,
+		AllowedEmailAddresses: raw.AllowedEmailAddresses,
+		AllowedURIPrefixes:    raw.AllowedURIPrefixes,
+		KeyTypes:              raw.KeyTypes,
+		HonorExtensions:       DefaultIssuancePolicy.HonorExtensions,
+	}
+	if raw.AllowedIPRanges != nil {
+		policy.AllowedIPRanges = make([]*net.IPNet, len(raw.AllowedIPRanges))
+		for i, value := range raw.AllowedIPRanges {
+			_, policy.AllowedIPRanges[i], err = net.ParseCIDR(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if raw.MaxValidity != "" {
+		policy.MaxValidity, err = time.ParseDuration(raw.MaxValidity)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, keyType := range raw.KeyTypes {
+		_, err = parseKeyType(keyType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if raw.HonorExtensions != nil {
+		policy.HonorExtensions, err = parseExtensionNames(raw.HonorExtensions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+// Check returns an error if the request or validity violates the policy.
+func (p *IssuancePolicy) Check(csr *x509.CertificateRequest, notBefore time.Time, notAfter time.Time) error {
+	if p.MaxValidity > 0 && notAfter.Sub(notBefore) > p.MaxValidity {
+		return fmt.Errorf("validity %v exceeds policy maximum %v", notAfter.Sub(notBefore), p.MaxValidity)
+	}
+	err := p.checkKeyType(csr.PublicKey)
+	if err != nil {
+		return err
+	}
+	err = checkSANTypes(csr)
+	if err != nil {
+		return err
+	}
+	// Many clients still match the common name as a DNS name.
+	commonName := csr.Subject.CommonName
+	if commonName != "" && p.AllowedDNSNames != nil && !matchesAny(commonName, p.AllowedDNSNames, matchDNSName) {
+		return fmt.Errorf("common name not allowed by policy: %s", commonName)
+	}
+	for _, name := range csr.DNSNames {
+		if p.AllowedDNSNames != nil && !matchesAny(name, p.AllowedDNSNames, matchDNSName) {
+			return fmt.Errorf("DNS name not allowed by policy: %s", name)
+		}
+	}
+	for _, ip := range csr.IPAddresses {
+		if p.AllowedIPRanges != nil && !containsIP(p.AllowedIPRanges, ip) {
+			return fmt.Errorf("IP address not allowed by policy: %s", ip)
+		}
+	}
+	for _, email := range csr.EmailAddresses {
+		if p.AllowedEmailAddresses != nil && !matchesAny(email, p.AllowedEmailAddresses, matchEmail) {
+			return fmt.Errorf("email address not allowed by policy: %s", email)
+		}
+	}
+	for _, uri := range csr.URIs {
+		if p.AllowedURIPrefixes != nil && !matchesAny(uri.String(), p.AllowedURIPrefixes, strings.HasPrefix) {
+			return fmt.Errorf("URI not allowed by policy: %s", uri)
+		}
+	}
+	return nil
+}
+
+// checkSANTypes rejects subject alternative names other than the DNS names,
+// IP addresses, email addresses and URIs the policy covers.
+func checkSANTypes(csr *x509.CertificateRequest) error {
+	for _, extension := range csr.Extensions {
+		if !extension.Id.Equal(oidSubjectAltName) {
+			continue
+		}
+		var names asn1.RawValue
+		_, err := asn1.Unmarshal(extension.Value, &names)
+		if err != nil {
+			return fmt.Errorf("invalid subject alternative name: %w", err)
+		}
+		rest := names.Bytes
+		for len(rest) > 0 {
+			var name asn1.RawValue
+			rest, err = asn1.Unmarshal(rest, &name)
+			if err != nil {
+				return fmt.Errorf("invalid subject alternative name: %w", err)
+			}
+			if name.Class != asn1.ClassContextSpecific {
+				return fmt.Errorf("invalid subject alternative name class: %d", name.Class)
+			}
+			switch name.Tag {
+			case sanEmail, sanDNS, sanURI, sanIP:
+			default:
+				return fmt.Errorf("subject alternative name type not allowed by policy: %d", name.Tag)
+			}
+		}
+	}
+	return nil
+}
+
+// FilterExtensions splits the extensions requested by csr into those the
+// policy honors and those it strips.
+func (p *IssuancePolicy) FilterExtensions(csr *x509.CertificateRequest) ([]pkix.Extension, []pkix.Extension) {
+	var honored, stripped []pkix.Extension
+	for _, extension := range csr.Extensions {
+		if containsOID(p.HonorExtensions, extension.Id) && !containsOID(unhonorableExtensions, extension.Id) {
+			honored = append(honored, extension)
+		} else {
+			stripped = append(stripped, extension)
+		}
+	}
+	return honored, stripped
+}
+
+func (p *IssuancePolicy) checkKeyType(key interface{}) error {
+	if len(p.KeyTypes) == 0 {
+		return nil
+	}
+	for _, keyType := range p.KeyTypes {
+		allowed, err := parseKeyType(keyType)
+		if err != nil {
+			return err
+		}
+		if allowed(key) {
+			return nil
+		}
+	}
+	return fmt.Errorf("key type not allowed by policy: %s", describeKeyType(key))
+}
+
+func parseKeyType(keyType string) (func(key interface{}) bool, error) {
+	switch keyType {
+	case "ecdsa-p256", "ecdsa-p384", "ecdsa-p521":
+		curveName := "P-" + strings.TrimPrefix(keyType, "ecdsa-p")
+		return func(key interface{}) bool {
+			ecKey, ok := key.(*ecdsa.PublicKey)
+			return ok && ecKey.Curve.Params().Name == curveName
+		}, nil
+	case "ed25519":
+		return func(key interface{}) bool {
+			_, ok := key.(ed25519.PublicKey)
+			return ok
+		}, nil
+	}
+	if strings.HasPrefix(keyType, "rsa-") {
+		bits, err := strconv.Atoi(strings.TrimPrefix(keyType, "rsa-"))
+		if err == nil && bits > 0 {
+			return func(key interface{}) bool {
+				rsaKey, ok := key.(*rsa.PublicKey)
+				return ok && rsaKey.N.BitLen() >= bits
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", keyType)
+}
+
+func describeKeyType(key interface{}) string {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("rsa-%d", key.(*rsa.PublicKey).N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ecdsa-" + strings.ToLower(strings.Replace(key.(*ecdsa.PublicKey).Curve.Params().Name, "-", "", 1))
+	case ed25519.PublicKey:
+		return "ed25519"
+	default:
+		return reflect.TypeOf(key).String()
+	}
+}
+
+func parseExtensionNames(names []string) ([]asn1.ObjectIdentifier, error) {
+	oids := make([]asn1.ObjectIdentifier, len(names))
+	for i, name := range names {
+		oid, ok := extensionNames[name]
+		if !ok {
+			var err error
+			oid, err = ParseOID(name)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported extension: %s", name)
+			}
+		}
+		if containsOID(unhonorableExtensions, oid) {
+			return nil, fmt.Errorf("requested extension can't be honored: %s", name)
+		}
+		oids[i] = oid
+	}
+	return oids, nil
+}
+
+func matchesAny(name string, patterns []string, match func(string, string) bool) bool {
+	for _, pattern := range patterns {
+		if match(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchDNSName(name string, pattern string) bool {
+	name = strings.ToLower(name)
+	pattern = strings.ToLower(pattern)
+	if strings.HasPrefix(pattern, "*.") {
+		label := strings.TrimSuffix(name, pattern[1:])
+		return label != name && label != "" && !strings.Contains(label, ".")
+	}
+	return name == pattern
+}
+
+func matchEmail(email string, pattern string) bool {
+	if strings.HasPrefix(pattern, "@") {
+		return strings.HasSuffix(strings.ToLower(email), strings.ToLower(pattern))
+	}
+	return strings.EqualFold(email, pattern)
+}
+
+func containsIP(ranges []*net.IPNet, ip net.IP) bool {
+	for _, ipRange := range ranges {
+		if ipRange.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsOID(oids []asn1.ObjectIdentifier, oid asn1.ObjectIdentifier) bool {
+	for _, candidate := range oids {
+		if candidate.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
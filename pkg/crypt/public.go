@@ -2,37 +2,41 @@ package crypt
 
 import (
 	"crypto"
+	"crypto/ecdh"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"fmt"
 	"github.com/credding/crypt/pkg/encoding"
-	"github.com/spf13/cobra"
-	"os"
 	"reflect"
 )
 
-var publicCommand = &cobra.Command{
-	Use:   "public",
-	Short: "Output the public key given a private key, or certificate on stdin",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		key, err := encoding.DecodePEM(os.Stdin)
-		if err != nil {
-			return err
-		}
-
-		chain, err := getPublicPEMChain(key)
-		if err != nil {
-			return err
-		}
-		return encoding.EncodePEM(os.Stdout, chain)
-	},
+// PublicKey returns the public key of a private or public key, certificate,
+// or certificate request.
+func PublicKey(key interface{}) (crypto.PublicKey, error) {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, *ecdh.PublicKey, ed25519.PublicKey:
+		return key, nil
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return key.(crypto.Signer).Public(), nil
+	case *ecdh.PrivateKey:
+		return key.(*ecdh.PrivateKey).PublicKey(), nil
+	case *x509.Certificate:
+		return key.(*x509.Certificate).PublicKey, nil
+	case *x509.CertificateRequest:
+		return key.(*x509.CertificateRequest).PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type: %v", reflect.TypeOf(key))
+	}
 }
 
-func getPublicPEMChain(chain encoding.PEMChain) (encoding.PEMChain, error) {
+// PublicChain replaces every item of a PEM chain with its public key.
+func PublicChain(chain encoding.PEMChain) (encoding.PEMChain, error) {
 	publicChain := make(encoding.PEMChain, len(chain))
 	for i, key := range chain {
-		publicKey, err := getPublicKey(key)
+		publicKey, err := PublicKey(key)
 		if err != nil {
 			return nil, err
 		}
@@ -41,17 +45,17 @@ func getPublicPEMChain(chain encoding.PEMChain) (encoding.PEMChain, error) {
 	return publicChain, nil
 }
 
-func getPublicKey(key interface{}) (interface{}, error) {
-	switch key.(type) {
-	case *rsa.PublicKey, *ecdsa.PublicKey:
-		return key, nil
-	case *rsa.PrivateKey, *ecdsa.PrivateKey:
-		return key.(crypto.Signer).Public(), nil
-	case *x509.Certificate:
-		return key.(*x509.Certificate).PublicKey, nil
-	case *x509.CertificateRequest:
-		return key.(*x509.CertificateRequest).PublicKey, nil
-	default:
-		return nil, fmt.Errorf("unsupported public key type: %v", reflect.TypeOf(key))
+// SPKIHash returns the SHA-256 hash of the DER subject public key info of a
+// private or public key, certificate, or certificate request.
+func SPKIHash(key interface{}) ([]byte, error) {
+	publicKey, err := PublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
 	}
+	hash := sha256.Sum256(der)
+	return hash[:], nil
 }
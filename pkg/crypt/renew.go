@@ -0,0 +1,164 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"time"
+)
+
+// Extensions x509.CreateCertificate generates from template fields, which
+// RenewalTemplate copies through the parsed fields rather than verbatim.
+var templateExtensions = []asn1.ObjectIdentifier{
+	{2, 5, 29, 14},              // subject key identifier
+	{2, 5, 29, 35},              // authority key identifier
+	{2, 5, 29, 15},              // key usage
+	{2, 5, 29, 37},              // extended key usage
+	{2, 5, 29, 19},              // basic constraints
+	{2, 5, 29, 17},              // subject alternative name
+	{2, 5, 29, 30},              // name constraints
+	{2, 5, 29, 31},              // CRL distribution points
+	{2, 5, 29, 32},              // certificate policies
+	{1, 3, 6, 1, 5, 5, 7, 1, 1}, // authority information access
+}
+
+// Extensions that describe the issuance of cert itself, which a renewal
+// must not carry over.
+var issuanceExtensions = []asn1.ObjectIdentifier{
+	{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}, // CT signed certificate timestamp list
+	{1, 3, 6, 1, 4, 1, 11129, 2, 4, 3}, // CT precertificate poison
+}
+
+// RenewCertificate issues a copy of cert with a fresh serial number and
+// validity. A nil publicKey keeps the certificate's key. The validity
+// defaults to that of cert, starting now.
+func RenewCertificate(cert *x509.Certificate, publicKey crypto.PublicKey, issuer *Issuer, opts *CertificateOptions) (*x509.Certificate, error) {
+	if issuer == nil || issuer.Key == nil {
+		return nil, errors.New("issuer key not provided")
+	}
+	if publicKey == nil {
+		publicKey = cert.PublicKey
+	}
+	template, err := RenewalTemplate(cert, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !samePublicKey(publicKey, cert.PublicKey) {
+		template.SubjectKeyId = nil
+	}
+	parent := issuer.Certificate
+	if parent == nil {
+		parent = template
+	}
+
+	certRaw, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, issuer.Key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(certRaw)
+}
+
+// RenewalTemplate returns a template cloning the subject, names, key usages,
+// constraints and extensions of cert, with a fresh serial number and
+// validity. The subject is copied as encoded, so certificates naming it as
+// their issuer still chain to the copy.
+func RenewalTemplate(cert *x509.Certificate, opts *CertificateOptions) (*x509.Certificate, error) {
+	if opts == nil {
+		opts = &CertificateOptions{}
+	}
+	serialNumber, err := SerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	notBefore := opts.NotBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now()
+	}
+	notAfter := opts.NotAfter
+	if notAfter.IsZero() {
+		notAfter = notBefore.Add(cert.NotAfter.Sub(cert.NotBefore))
+	}
+
+	return &x509.Certificate{
+		SerialNumber:                serialNumber,
+		RawSubject:                  cert.RawSubject,
+		Subject:                     cert.Subject,
+		NotBefore:                   notBefore,
+		NotAfter:                    notAfter,
+		KeyUsage:                    cert.KeyUsage,
+		ExtKeyUsage:                 cert.ExtKeyUsage,
+		UnknownExtKeyUsage:          cert.UnknownExtKeyUsage,
+		BasicConstraintsValid:       cert.BasicConstraintsValid,
+		IsCA:                        cert.IsCA,
+		MaxPathLen:                  cert.MaxPathLen,
+		MaxPathLenZero:              cert.MaxPathLenZero,
+		SubjectKeyId:                cert.SubjectKeyId,
+		DNSNames:                    cert.DNSNames,
+		EmailAddresses:              cert.EmailAddresses,
+		IPAddresses:                 cert.IPAddresses,
+		URIs:                        cert.URIs,
+		PermittedDNSDomainsCritical: cert.PermittedDNSDomainsCritical,
+		PermittedDNSDomains:         cert.PermittedDNSDomains,
+		ExcludedDNSDomains:          cert.ExcludedDNSDomains,
+		PermittedIPRanges:           cert.PermittedIPRanges,
+		ExcludedIPRanges:            cert.ExcludedIPRanges,
+		PermittedEmailAddresses:     cert.PermittedEmailAddresses,
+		ExcludedEmailAddresses:      cert.ExcludedEmailAddresses,
+		PermittedURIDomains:         cert.PermittedURIDomains,
+		ExcludedURIDomains:          cert.ExcludedURIDomains,
+		CRLDistributionPoints:       cert.CRLDistributionPoints,
+		OCSPServer:                  cert.OCSPServer,
+		IssuingCertificateURL:       cert.IssuingCertificateURL,
+		PolicyIdentifiers:           cert.PolicyIdentifiers,
+		ExtraExtensions:             extraExtensions(cert),
+	}, nil
+}
+
+// CreateRenewalRequest creates a certificate signing request for the subject
+// and subject alternative names of cert, signed by key.
+func CreateRenewalRequest(cert *x509.Certificate, key crypto.Signer) (*x509.CertificateRequest, error) {
+	signatureAlgorithm, err := SignatureAlgorithm(key)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.CertificateRequest{
+		SignatureAlgorithm: signatureAlgorithm,
+		RawSubject:         cert.RawSubject,
+		Subject:            cert.Subject,
+		DNSNames:           cert.DNSNames,
+		EmailAddresses:     cert.EmailAddresses,
+		IPAddresses:        cert.IPAddresses,
+		URIs:               cert.URIs,
+	}
+	csrRaw, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificateRequest(csrRaw)
+}
+
+func extraExtensions(cert *x509.Certificate) []pkix.Extension {
+	var extensions []pkix.Extension
+	for _, extension := range cert.Extensions {
+		if !containsOID(templateExtensions, extension.Id) && !containsOID(issuanceExtensions, extension.Id) {
+			extensions = append(extensions, extension)
+		}
+	}
+	return extensions
+}
+
+func samePublicKey(a crypto.PublicKey, b crypto.PublicKey) bool {
+	aDer, err := x509.MarshalPKIXPublicKey(a)
+	if err != nil {
+		return false
+	}
+	bDer, err := x509.MarshalPKIXPublicKey(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aDer, bDer)
+}
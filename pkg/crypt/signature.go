@@ -0,0 +1,213 @@
+package crypt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"reflect"
+)
+
+// MaxEd25519MessageSize bounds the messages SignReader and VerifyReader
+// accept for Ed25519 keys, which sign the message itself rather than a
+// digest, so it is read into memory.
+const MaxEd25519MessageSize = 64 << 20
+
+var signatureHashes = map[string]crypto.Hash{
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+// SignatureOptions configure SignReader and VerifyReader.
+type SignatureOptions struct {
+	// Hash is the message digest, defaulting to SHA-256. Ed25519 keys sign
+	// the message itself, so it must not be set for them.
+	Hash crypto.Hash
+	// PSS selects RSA-PSS over RSA PKCS #1 v1.5.
+	PSS bool
+	// RawECDSA encodes ECDSA signatures as fixed size r || s rather than DER.
+	RawECDSA bool
+}
+
+// ParseSignatureHash returns the hash named sha256, sha384, or sha512.
+func ParseSignatureHash(name string) (crypto.Hash, error) {
+	hash, ok := signatureHashes[name]
+	if !ok {
+		return 0, fmt.Errorf("unsupported hash: %s", name)
+	}
+	return hash, nil
+}
+
+// SignReader signs the digest of everything read from in, or for Ed25519
+// keys the message itself.
+func SignReader(in io.Reader, key crypto.Signer, opts *SignatureOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &SignatureOptions{}
+	}
+	if edKey, ok := key.(ed25519.PrivateKey); ok {
+		message, err := readEd25519Message(in, opts)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.Sign(edKey, message), nil
+	}
+	hash := signatureHash(opts)
+	digest, err := digestReader(in, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		if opts.PSS {
+			return rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), hash, digest)
+	case *ecdsa.PrivateKey:
+		signature, err := ecdsa.SignASN1(rand.Reader, key.(*ecdsa.PrivateKey), digest)
+		if err != nil || !opts.RawECDSA {
+			return signature, err
+		}
+		return ecdsaDERToRaw(signature, key.(*ecdsa.PrivateKey).Curve.Params().BitSize)
+	default:
+		return nil, fmt.Errorf("unsupported private key type: %v", reflect.TypeOf(key))
+	}
+}
+
+// VerifyReader verifies a signature over the digest of everything read from
+// in, or for Ed25519 keys over the message itself.
+func VerifyReader(in io.Reader, key crypto.PublicKey, signature []byte, opts *SignatureOptions) error {
+	if opts == nil {
+		opts = &SignatureOptions{}
+	}
+	if edKey, ok := key.(ed25519.PublicKey); ok {
+		message, err := readEd25519Message(in, opts)
+		if err != nil {
+			return err
+		}
+		if !ed25519.Verify(edKey, message, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	hash := signatureHash(opts)
+	digest, err := digestReader(in, hash)
+	if err != nil {
+		return err
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if opts.PSS {
+			return rsa.VerifyPSS(key.(*rsa.PublicKey), hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+		}
+		return rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), hash, digest, signature)
+	case *ecdsa.PublicKey:
+		if opts.RawECDSA {
+			signature, err = ecdsaRawToDER(signature, key.(*ecdsa.PublicKey).Curve.Params().BitSize)
+			if err != nil {
+				return err
+			}
+		}
+		if !ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), digest, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type: %v", reflect.TypeOf(key))
+	}
+}
+
+// VerifyChain verifies that each certificate of a chain is issued by the next,
+// ending in one of roots. Without roots there is nothing to trust, so it
+// fails rather than accepting any self-signed chain.
+func VerifyChain(chain []*x509.Certificate, roots *x509.CertPool) error {
+	if len(chain) == 0 {
+		return errors.New("empty certificate chain")
+	}
+	if roots == nil {
+		return errors.New("no trusted roots to verify the certificate chain against")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+func signatureHash(opts *SignatureOptions) crypto.Hash {
+	if opts.Hash == 0 {
+		return crypto.SHA256
+	}
+	return opts.Hash
+}
+
+func readEd25519Message(in io.Reader, opts *SignatureOptions) ([]byte, error) {
+	if opts.Hash != 0 {
+		return nil, errors.New("ed25519 signatures do not take a hash")
+	}
+	message, err := ioutil.ReadAll(io.LimitReader(in, MaxEd25519MessageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(message) > MaxEd25519MessageSize {
+		return nil, fmt.Errorf("ed25519 messages are limited to %d bytes", MaxEd25519MessageSize)
+	}
+	return message, nil
+}
+
+func digestReader(in io.Reader, hash crypto.Hash) ([]byte, error) {
+	if !hash.Available() {
+		return nil, fmt.Errorf("unavailable hash: %v", hash)
+	}
+	h := hash.New()
+	_, err := io.Copy(h, in)
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+func ecdsaDERToRaw(signature []byte, bitSize int) ([]byte, error) {
+	var sig ecdsaSignature
+	_, err := asn1.Unmarshal(signature, &sig)
+	if err != nil {
+		return nil, err
+	}
+	size := (bitSize + 7) / 8
+	raw := make([]byte, 2*size)
+	sig.R.FillBytes(raw[:size])
+	sig.S.FillBytes(raw[size:])
+	return raw, nil
+}
+
+func ecdsaRawToDER(signature []byte, bitSize int) ([]byte, error) {
+	size := (bitSize + 7) / 8
+	if len(signature) != 2*size {
+		return nil, fmt.Errorf("expected a raw signature of %d bytes, got %d", 2*size, len(signature))
+	}
+	return asn1.Marshal(ecdsaSignature{
+		R: new(big.Int).SetBytes(signature[:size]),
+		S: new(big.Int).SetBytes(signature[size:]),
+	})
+}
@@ -0,0 +1,46 @@
+package encoding
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ByteFormats are the formats EncodeBytes and DecodeBytes accept.
+var ByteFormats = []string{"base64", "base64url", "hex", "binary"}
+
+func EncodeBytes(format string, data []byte) ([]byte, error) {
+	switch format {
+	case "base64":
+		return []byte(base64.StdEncoding.EncodeToString(data)), nil
+	case "base64url":
+		return []byte(base64.RawURLEncoding.EncodeToString(data)), nil
+	case "hex":
+		return []byte(hex.EncodeToString(data)), nil
+	case "binary", "raw":
+		return data, nil
+	default:
+		return nil, unsupportedByteFormat(format)
+	}
+}
+
+func DecodeBytes(format string, data []byte) ([]byte, error) {
+	text := strings.TrimSpace(string(data))
+	switch format {
+	case "base64":
+		return base64.StdEncoding.DecodeString(text)
+	case "base64url":
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(text, "="))
+	case "hex":
+		return hex.DecodeString(text)
+	case "binary", "raw":
+		return data, nil
+	default:
+		return nil, unsupportedByteFormat(format)
+	}
+}
+
+func unsupportedByteFormat(format string) error {
+	return fmt.Errorf("unsupported format %s: expected %s", format, strings.Join(ByteFormats, ", "))
+}
@@ -0,0 +1,56 @@
+package encoding
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/credding/crypt/pkg/cms"
+	"github.com/square/go-jose/v3"
+)
+
+// ExtractCertificates returns the certificates in PEM, DER, JWK x5c, or JWK
+// set x5c data. Anything else, including PEM blocks other than certificates,
+// is skipped.
+func ExtractCertificates(data []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				certs = append(certs, cert)
+			}
+		case "PKCS7", "CMS":
+			chain, err := cms.ParseCertificates(block.Bytes)
+			if err == nil {
+				certs = append(certs, chain...)
+			}
+		}
+	}
+	if len(certs) > 0 {
+		return certs
+	}
+
+	der, err := x509.ParseCertificates(data)
+	if err == nil {
+		return der
+	}
+	decoded, err := Encodings{JWKs, JWK}.Unmarshal(data)
+	if err != nil {
+		return nil
+	}
+	switch decoded.(type) {
+	case *jose.JSONWebKeySet:
+		for _, key := range decoded.(*jose.JSONWebKeySet).Keys {
+			certs = append(certs, key.Certificates...)
+		}
+	case *jose.JSONWebKey:
+		certs = decoded.(*jose.JSONWebKey).Certificates
+	}
+	return certs
+}
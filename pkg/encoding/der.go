@@ -0,0 +1,43 @@
+package encoding
+
+import (
+	"crypto/x509"
+)
+
+var DER = &derFormat{}
+
+type derFormat struct{}
+
+func (*derFormat) Type() string {
+	return "der"
+}
+
+// TryUnmarshal parses a DER key in any format EncodePEMFormat writes, or a
+// DER certificate, CSR, or CRL, as a single item PEM chain.
+func (*derFormat) TryUnmarshal(data []byte) (interface{}, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(data); err == nil {
+		return PEMChain{key}, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(data); err == nil {
+		return PEMChain{key}, nil
+	}
+	if key, err := x509.ParseECPrivateKey(data); err == nil {
+		return PEMChain{key}, nil
+	}
+	if key, err := x509.ParsePKIXPublicKey(data); err == nil {
+		return PEMChain{key}, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(data); err == nil {
+		return PEMChain{key}, nil
+	}
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return PEMChain{cert}, nil
+	}
+	if csr, err := x509.ParseCertificateRequest(data); err == nil {
+		return PEMChain{csr}, nil
+	}
+	if crl, err := x509.ParseRevocationList(data); err == nil {
+		return PEMChain{crl}, nil
+	}
+	return nil, UnsupportedEncoding
+}
@@ -17,8 +17,16 @@ func (*jwksFormat) Type() string {
 }
 
 func (*jwksFormat) TryUnmarshal(data []byte) (interface{}, error) {
+	members := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &members)
+	if err != nil {
+		return nil, handleTryUnmarshalError(err)
+	}
+	if _, ok := members["keys"]; !ok {
+		return nil, UnsupportedEncoding
+	}
 	jwks := &jose.JSONWebKeySet{}
-	err := json.Unmarshal(data, jwks)
+	err = json.Unmarshal(data, jwks)
 	if err != nil {
 		return nil, handleTryUnmarshalError(err)
 	}
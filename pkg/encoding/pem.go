@@ -1,13 +1,17 @@
 package encoding
 
 import (
+	"crypto/ecdh"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"github.com/credding/crypt/pkg/cms"
 	"io"
 	"io/ioutil"
+	"os"
 	"reflect"
 )
 
@@ -41,7 +45,11 @@ func UnmarshalPEM(data []byte) (PEMChain, error) {
 		if err != nil {
 			return nil, err
 		}
-		blocks = append(blocks, result)
+		if chain, ok := result.(PEMChain); ok {
+			blocks = append(blocks, chain...)
+		} else {
+			blocks = append(blocks, result)
+		}
 		block, data = pem.Decode(data)
 	}
 	if len(blocks) == 0 {
@@ -50,47 +58,146 @@ func UnmarshalPEM(data []byte) (PEMChain, error) {
 	return blocks, nil
 }
 
+// Key formats for EncodePEMFormat and MarshalDER. The empty format is
+// PKCS8 for private keys and PKIX for public keys.
+const (
+	PKCS1 = "pkcs1"
+	PKCS8 = "pkcs8"
+	SEC1  = "sec1"
+	PKIX  = "pkix"
+)
+
 func EncodePEM(out io.Writer, data interface{}) error {
-	switch data.(type) {
-	case PEMChain:
-		for _, data := range data.(PEMChain) {
-			err := EncodePEM(out, data)
+	return EncodePEMFormat(out, data, "")
+}
+
+// EncodePEMFormat encodes data like EncodePEM, writing keys in a key format.
+// PKCS1 encodes RSA keys, SEC1 EC private keys, PKCS8 private keys, and
+// PKIX public keys.
+func EncodePEMFormat(out io.Writer, data interface{}, format string) error {
+	if chain, ok := data.(PEMChain); ok {
+		for _, data := range chain {
+			err := EncodePEMFormat(out, data, format)
 			if err != nil {
 				return err
 			}
 		}
 		return nil
-	case *rsa.PublicKey, *ecdsa.PublicKey:
-		encoded, err := x509.MarshalPKIXPublicKey(data)
+	}
+	block, err := marshalBlock(data, format)
+	if err != nil {
+		return err
+	}
+	return pem.Encode(out, block)
+}
+
+// MarshalDER returns the DER encoding of a key in a key format, or of a
+// certificate, CSR, or CRL.
+func MarshalDER(data interface{}, format string) ([]byte, error) {
+	block, err := marshalBlock(data, format)
+	if err != nil {
+		return nil, err
+	}
+	return block.Bytes, nil
+}
+
+func marshalBlock(data interface{}, format string) (*pem.Block, error) {
+	switch data.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, *ecdh.PublicKey, ed25519.PublicKey:
+		return marshalPublicKey(data, format)
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, *ecdh.PrivateKey, ed25519.PrivateKey:
+		return marshalPrivateKey(data, format)
+	case *x509.Certificate:
+		return &pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: data.(*x509.Certificate).Raw,
+		}, nil
+	case *x509.CertificateRequest:
+		return &pem.Block{
+			Type:  "CERTIFICATE REQUEST",
+			Bytes: data.(*x509.CertificateRequest).Raw,
+		}, nil
+	case *x509.RevocationList:
+		return &pem.Block{
+			Type:  "X509 CRL",
+			Bytes: data.(*x509.RevocationList).Raw,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported pem data type: %v", reflect.TypeOf(data))
+	}
+}
+
+func marshalPublicKey(key interface{}, format string) (*pem.Block, error) {
+	switch format {
+	case "", PKIX:
+		encoded, err := x509.MarshalPKIXPublicKey(key)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		return pem.Encode(out, &pem.Block{
+		return &pem.Block{
 			Type:  "PUBLIC KEY",
 			Bytes: encoded,
-		})
-	case *rsa.PrivateKey, *ecdsa.PrivateKey:
-		encoded, err := x509.MarshalPKCS8PrivateKey(data)
+		}, nil
+	case PKCS1:
+		if rsaKey, ok := key.(*rsa.PublicKey); ok {
+			return &pem.Block{
+				Type:  "RSA PUBLIC KEY",
+				Bytes: x509.MarshalPKCS1PublicKey(rsaKey),
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported %s public key type: %v", format, reflect.TypeOf(key))
+}
+
+func marshalPrivateKey(key interface{}, format string) (*pem.Block, error) {
+	switch format {
+	case "", PKCS8:
+		encoded, err := x509.MarshalPKCS8PrivateKey(key)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		return pem.Encode(out, &pem.Block{
+		return &pem.Block{
 			Type:  "PRIVATE KEY",
 			Bytes: encoded,
-		})
-	case *x509.Certificate:
-		return pem.Encode(out, &pem.Block{
-			Type:  "CERTIFICATE",
-			Bytes: data.(*x509.Certificate).Raw,
-		})
-	case *x509.CertificateRequest:
-		return pem.Encode(out, &pem.Block{
-			Type:  "CERTIFICATE REQUEST",
-			Bytes: data.(*x509.CertificateRequest).Raw,
-		})
-	default:
-		return fmt.Errorf("unsupported pem data type: %v", reflect.TypeOf(data))
+		}, nil
+	case PKCS1:
+		if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+			return &pem.Block{
+				Type:  "RSA PRIVATE KEY",
+				Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
+			}, nil
+		}
+	case SEC1:
+		if ecKey, ok := key.(*ecdsa.PrivateKey); ok {
+			encoded, err := x509.MarshalECPrivateKey(ecKey)
+			if err != nil {
+				return nil, err
+			}
+			return &pem.Block{
+				Type:  "EC PRIVATE KEY",
+				Bytes: encoded,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported %s private key type: %v", format, reflect.TypeOf(key))
+}
+
+// WritePEMFile encodes data to a new or truncated file with the given
+// permissions.
+func WritePEMFile(path string, data interface{}, mode os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	err = file.Chmod(mode)
+	if err == nil {
+		err = EncodePEM(file, data)
+	}
+	closeErr := file.Close()
+	if err != nil {
+		return err
 	}
+	return closeErr
 }
 
 func parsePEMBlock(block *pem.Block) (interface{}, error) {
@@ -109,7 +216,23 @@ func parsePEMBlock(block *pem.Block) (interface{}, error) {
 		return x509.ParseCertificate(block.Bytes)
 	case "CERTIFICATE REQUEST":
 		return x509.ParseCertificateRequest(block.Bytes)
+	case "X509 CRL":
+		return x509.ParseRevocationList(block.Bytes)
+	case "PKCS7", "CMS":
+		return parsePKCS7Block(block.Bytes)
 	default:
 		return nil, fmt.Errorf("unsupported pem block type: %s", block.Type)
 	}
 }
+
+func parsePKCS7Block(data []byte) (PEMChain, error) {
+	certs, err := cms.ParseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	chain := make(PEMChain, len(certs))
+	for i, cert := range certs {
+		chain[i] = cert
+	}
+	return chain, nil
+}
@@ -0,0 +1,16 @@
+package encoding
+
+var Raw = &rawFormat{}
+
+type rawFormat struct{}
+
+func (*rawFormat) Type() string {
+	return "raw"
+}
+
+func (*rawFormat) TryUnmarshal(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, UnsupportedEncoding
+	}
+	return data, nil
+}
@@ -8,6 +8,10 @@ func FileRead() *File {
 	return &File{flag: os.O_RDONLY}
 }
 
+func FileWrite() *File {
+	return &File{flag: os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode: 0666}
+}
+
 func FileReadWrite() *File {
 	return &File{flag: os.O_RDWR|os.O_CREATE, mode: 0666}
 }
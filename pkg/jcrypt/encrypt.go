@@ -1,103 +1,255 @@
 package jcrypt
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/rsa"
 	"fmt"
-	"github.com/credding/crypt/pkg/flags"
-	"github.com/spf13/cobra"
 	"github.com/square/go-jose/v3"
-	"os"
+	"math"
+	"strings"
 )
 
-var (
-	encryptKey = flags.FileRead()
-	encryptAlg string
-	encryptEnc string
-	encryptKid string
+const DefaultContentEncryption = jose.A128CBC_HS256
+
+const (
+	DefaultPBES2Algorithm = jose.PBES2_HS256_A128KW
+	DefaultPBES2Count     = 100000
+	MinimumPBES2Count     = 1000
+	MaximumPBES2Count     = 10 * DefaultPBES2Count
 )
 
-var encryptCommand = &cobra.Command{
-	Use:   "encrypt [key]",
-	Short: "Generate a JWE given a payload on stdin",
-	Args:  cobra.MaximumNArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		payload, err := decodePlainPayload(os.Stdin)
-		if err != nil {
-			return err
-		}
-		key, err := getEncryptingKey(encryptKey.File(), args, encryptKid)
-		if err != nil {
-			return err
-		}
+// EncryptOptions configure Encrypt and EncryptWithPassword.
+type EncryptOptions struct {
+	// KeyAlgorithm is the key management algorithm. When empty it is taken
+	// from the key, or chosen from the key type.
+	KeyAlgorithm jose.KeyAlgorithm
+	// ContentEncryption defaults to DefaultContentEncryption.
+	ContentEncryption jose.ContentEncryption
+	// Headers are additional protected headers.
+	Headers map[jose.HeaderKey]interface{}
+	// EmbedCertificates adds the key certificate chain, if any, as the x5c
+	// header unless Headers already sets one.
+	EmbedCertificates bool
+	// Compress deflates the payload before encryption.
+	Compress bool
+	// KeyID is the kid of a password recipient.
+	KeyID string
+	// PBES2Count is the PBES2 iteration count, defaulting to
+	// DefaultPBES2Count.
+	PBES2Count int
+}
 
-		encrypter, err := getEncrypter(encryptAlg, encryptEnc, key)
-		if err != nil {
-			return err
-		}
-		jwe, err := encrypter.Encrypt(payload)
-		if err != nil {
-			return err
-		}
-		compact, err := jwe.CompactSerialize()
-		if err != nil {
-			return err
-		}
-		_, err = fmt.Fprintln(os.Stdout, compact)
+// DecryptOptions configure Decrypt and DecryptWithPassword.
+type DecryptOptions struct {
+	// KeyID selects the decryption key, defaulting to the kid header.
+	KeyID string
+	// MinimumPBES2Count is the lowest accepted PBES2 iteration count,
+	// defaulting to MinimumPBES2Count.
+	MinimumPBES2Count int
+	// MaximumPBES2Count is the highest accepted PBES2 iteration count,
+	// defaulting to MaximumPBES2Count, so a token cannot demand minutes of
+	// key derivation.
+	MaximumPBES2Count int
+}
+
+// Encrypt encrypts payload to key, returning the JWE in compact
+// serialization. Private keys are encrypted to their public key.
+func Encrypt(ctx context.Context, payload []byte, key *jose.JSONWebKey, opts *EncryptOptions) (string, error) {
+	if opts == nil {
+		opts = &EncryptOptions{}
+	}
+	key = encryptionKey(key)
+	enc := contentEncryption(opts)
+	recipient := jose.Recipient{
+		Algorithm: opts.KeyAlgorithm,
+		Key:       key,
+	}
+	if recipient.Algorithm == "" {
+		recipient.Algorithm = KeyAlgorithm(key, enc)
+	}
+	if bytes, ok := symmetricKey(key); ok {
+		err := checkKeyAlgorithmKeySize(recipient.Algorithm, enc, bytes)
 		if err != nil {
-			return err
+			return "", err
 		}
-
-		return nil
-	},
+	}
+	headers := headerCopy(opts.Headers)
+	if _, ok := headers["x5c"]; !ok && opts.EmbedCertificates && len(key.Certificates) > 0 {
+		headers["x5c"] = certificateHeader(key)
+	}
+	return encrypt(ctx, payload, recipient, headers, opts)
 }
 
-func init() {
-	encryptCommand.Flags().SortFlags = false
-	encryptCommand.Flags().VarP(encryptKey, "key", "k", "Key file")
-	encryptCommand.Flags().StringVarP(&encryptAlg, "alg", "a", "", "Key algorithm (default auto)")
-	encryptCommand.Flags().StringVarP(&encryptEnc, "enc", "e", defaultContentEncryption, "Encryption algorithm")
-	encryptCommand.Flags().StringVar(&encryptKid, "kid", "", "Key ID")
+// EncryptWithPassword encrypts payload with a PBES2 key derived from password.
+func EncryptWithPassword(ctx context.Context, payload []byte, password []byte, opts *EncryptOptions) (string, error) {
+	if opts == nil {
+		opts = &EncryptOptions{}
+	}
+	alg := opts.KeyAlgorithm
+	if alg == "" {
+		alg = DefaultPBES2Algorithm
+	}
+	if !IsPBES2(string(alg)) {
+		return "", fmt.Errorf("expected a PBES2 key algorithm for password encryption, got %s", alg)
+	}
+	count := opts.PBES2Count
+	if count == 0 {
+		count = DefaultPBES2Count
+	}
+	if count < MinimumPBES2Count || count > MaximumPBES2Count {
+		return "", fmt.Errorf("p2c must be between %d and %d", MinimumPBES2Count, MaximumPBES2Count)
+	}
+	if len(password) == 0 {
+		return "", emptyKeyError
+	}
+	recipient := jose.Recipient{
+		Algorithm:  alg,
+		Key:        password,
+		KeyID:      opts.KeyID,
+		PBES2Count: count,
+	}
+	return encrypt(ctx, payload, recipient, headerCopy(opts.Headers), opts)
 }
 
-func getEncryptingKey(keyFile *os.File, args []string, kid string) (*jose.JSONWebKey, error) {
-	key, err := getKey(keyFile, args, kid)
+// Decrypt decrypts a compact JWE with key material as accepted by SelectKey.
+func Decrypt(ctx context.Context, compact string, key interface{}, opts *DecryptOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &DecryptOptions{}
+	}
+	err := checkContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	k := key.Public()
-	return &k, nil
+
+	jwe, err := jose.ParseEncrypted(compact)
+	if err != nil {
+		return nil, err
+	}
+	kid := opts.KeyID
+	if kid == "" {
+		kid = jwe.Header.KeyID
+	}
+	jwk, err := SelectKey(key, kid)
+	if err != nil {
+		return nil, err
+	}
+	if IsPBES2(jwe.Header.Algorithm) {
+		err = checkPBES2Count(jwe.Header, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jwe.Decrypt(jwk)
 }
 
-func getEncrypter(alg string, enc string, key *jose.JSONWebKey) (jose.Encrypter, error) {
-	encryption := jose.ContentEncryption(enc)
-	recipient := jose.Recipient{
-		Algorithm: getKeyAlgorithm(alg, key),
-		Key: key,
+// DecryptWithPassword decrypts a PBES2 encrypted compact JWE, rejecting
+// iteration counts outside the configured range.
+func DecryptWithPassword(ctx context.Context, compact string, password []byte, opts *DecryptOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &DecryptOptions{}
+	}
+	err := checkContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jwe, err := jose.ParseEncrypted(compact)
+	if err != nil {
+		return nil, err
 	}
-	return jose.NewEncrypter(encryption, recipient, nil)
+	if !IsPBES2(jwe.Header.Algorithm) {
+		return nil, fmt.Errorf("expected a PBES2 key algorithm, got %s", jwe.Header.Algorithm)
+	}
+	err = checkPBES2Count(jwe.Header, opts)
+	if err != nil {
+		return nil, err
+	}
+	return jwe.Decrypt(password)
 }
 
-func getKeyAlgorithm(alg string, key *jose.JSONWebKey) jose.KeyAlgorithm {
-	if alg != "" {
-		return jose.KeyAlgorithm(alg)
+// IsPasswordEncrypted reports whether a compact JWE is encrypted with PBES2.
+func IsPasswordEncrypted(compact string) (bool, error) {
+	jwe, err := jose.ParseEncrypted(compact)
+	if err != nil {
+		return false, err
 	}
+	return IsPBES2(jwe.Header.Algorithm), nil
+}
+
+// IsPBES2 reports whether alg is a PBES2 key algorithm.
+func IsPBES2(alg string) bool {
+	return strings.HasPrefix(alg, "PBES2-")
+}
+
+// KeyAlgorithm returns the key management algorithm of key, or the default
+// for its key type and the content encryption.
+func KeyAlgorithm(key *jose.JSONWebKey, enc jose.ContentEncryption) jose.KeyAlgorithm {
 	if key.Algorithm != "" {
 		return jose.KeyAlgorithm(key.Algorithm)
 	}
-	return defaultKeyAlgorithm(key)
+	return defaultKeyAlgorithm(key, enc)
 }
 
-func defaultKeyAlgorithm(key *jose.JSONWebKey) jose.KeyAlgorithm {
+func defaultKeyAlgorithm(key *jose.JSONWebKey, enc jose.ContentEncryption) jose.KeyAlgorithm {
 	switch key.Key.(type) {
 	case *rsa.PublicKey:
 		return jose.RSA_OAEP
 	case *ecdsa.PublicKey:
 		return jose.ECDH_ES
 	case []byte:
-		return jose.DIRECT
+		return defaultSymmetricKeyAlgorithm(key.Key.([]byte), enc)
 	}
 	return ""
 }
 
-const defaultContentEncryption = string(jose.A128CBC_HS256)
+func encrypt(ctx context.Context, payload []byte, recipient jose.Recipient, headers map[jose.HeaderKey]interface{}, opts *EncryptOptions) (string, error) {
+	err := checkContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	options := &jose.EncrypterOptions{ExtraHeaders: headers}
+	if opts.Compress {
+		options.Compression = jose.DEFLATE
+	}
+	encrypter, err := jose.NewEncrypter(contentEncryption(opts), recipient, options)
+	if err != nil {
+		return "", err
+	}
+	jwe, err := encrypter.Encrypt(payload)
+	if err != nil {
+		return "", err
+	}
+	return jwe.CompactSerialize()
+}
+
+func contentEncryption(opts *EncryptOptions) jose.ContentEncryption {
+	if opts.ContentEncryption == "" {
+		return DefaultContentEncryption
+	}
+	return opts.ContentEncryption
+}
+
+func checkPBES2Count(header jose.Header, opts *DecryptOptions) error {
+	minimum := opts.MinimumPBES2Count
+	if minimum == 0 {
+		minimum = MinimumPBES2Count
+	}
+	maximum := opts.MaximumPBES2Count
+	if maximum == 0 {
+		maximum = MaximumPBES2Count
+	}
+	p2c, ok := header.ExtraHeaders["p2c"].(float64)
+	if !ok || p2c != math.Trunc(p2c) {
+		return fmt.Errorf("missing or invalid p2c header")
+	}
+	// The range is checked before converting, as the float may not fit.
+	if p2c < float64(minimum) {
+		return fmt.Errorf("p2c is below the minimum of %d", minimum)
+	}
+	if p2c > float64(maximum) {
+		return fmt.Errorf("p2c is above the maximum of %d", maximum)
+	}
+	return nil
+}
@@ -1,32 +1,26 @@
+// Package jcrypt signs, verifies, encrypts and decrypts JOSE objects, and
+// selects keys from the PEM, JWK and raw key material they are given.
 package jcrypt
 
 import (
-	"github.com/spf13/cobra"
-	"os"
+	"context"
+	"errors"
+	"github.com/square/go-jose/v3"
 )
 
-var rootCommand = &cobra.Command{
-	Use:   "jcrypt",
-	Short: "Simple JWE cryptography toolset",
-}
+var emptyKeyError = errors.New("key not provided")
 
-func init() {
-	cobra.EnableCommandSorting = false
-	rootCommand.AddCommand(
-		jwksCommand,
-		publicCommand,
-		claimsCommand,
-		signCommand,
-		verifyCommand,
-		encryptCommand,
-		decryptCommand,
-		base64Command,
-	)
+func checkContext(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Err()
 }
 
-func Execute() {
-	err := rootCommand.Execute()
-	if err != nil {
-		os.Exit(1)
+func headerCopy(headers map[jose.HeaderKey]interface{}) map[jose.HeaderKey]interface{} {
+	copied := make(map[jose.HeaderKey]interface{}, len(headers))
+	for key, value := range headers {
+		copied[key] = value
 	}
+	return copied
 }
@@ -0,0 +1,132 @@
+package jcrypt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/encoding"
+	"github.com/square/go-jose/v3"
+	"reflect"
+)
+
+// KeyEncodings are the encodings DecodeKey accepts, in the order they are tried.
+var KeyEncodings = encoding.Encodings{
+	encoding.PEM,
+	encoding.JWKs,
+	encoding.JWK,
+	encoding.Base64,
+	encoding.Base64URL,
+}
+
+// DecodeKey decodes key material given as PEM, a JWK set, a JWK, or base64
+// encoded symmetric key bytes.
+func DecodeKey(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, emptyKeyError
+	}
+	return KeyEncodings.Unmarshal(data)
+}
+
+// SelectKey returns the JWK for decoded key material. A kid picks the key out
+// of a JWK set, and is assigned to keys that do not carry their own.
+func SelectKey(key interface{}, kid string) (*jose.JSONWebKey, error) {
+	switch key.(type) {
+	case encoding.PEMChain:
+		return selectPEMKey(key.(encoding.PEMChain), kid)
+	case *jose.JSONWebKeySet:
+		return selectJWKsKey(key.(*jose.JSONWebKeySet), kid)
+	case *jose.JSONWebKey:
+		return selectJWKKey(key.(*jose.JSONWebKey), kid)
+	case []byte:
+		return &jose.JSONWebKey{Key: key, KeyID: kid}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %v", reflect.TypeOf(key))
+	}
+}
+
+// PEMChainToJWK converts a PEM key or certificate chain to a JWK. Any
+// certificates in the chain are kept as the JWK certificate chain.
+func PEMChainToJWK(chain encoding.PEMChain) (*jose.JSONWebKey, error) {
+	first := chain[0]
+	switch first.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey, *ecdsa.PrivateKey, *ecdsa.PublicKey, ed25519.PrivateKey, ed25519.PublicKey:
+		return &jose.JSONWebKey{Key: first, Certificates: PEMChainCertificates(chain[1:])}, nil
+	case *x509.Certificate:
+		return &jose.JSONWebKey{Key: first.(*x509.Certificate).PublicKey, Certificates: PEMChainCertificates(chain)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %v", reflect.TypeOf(first))
+	}
+}
+
+// PEMChainCertificates returns the certificates in a PEM chain.
+func PEMChainCertificates(chain encoding.PEMChain) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for _, key := range chain {
+		if cert, ok := key.(*x509.Certificate); ok {
+			certs = append(certs, cert)
+		}
+	}
+	return certs
+}
+
+// PublicKeySet returns the public keys of a JWK set.
+func PublicKeySet(jwks *jose.JSONWebKeySet) *jose.JSONWebKeySet {
+	publicJWKs := make([]jose.JSONWebKey, len(jwks.Keys))
+	for i, jwk := range jwks.Keys {
+		publicJWKs[i] = jwk.Public()
+	}
+	return &jose.JSONWebKeySet{Keys: publicJWKs}
+}
+
+func selectPEMKey(chain encoding.PEMChain, kid string) (*jose.JSONWebKey, error) {
+	key, err := PEMChainToJWK(chain)
+	if err != nil {
+		return nil, err
+	}
+	key.KeyID = kid
+	return key, nil
+}
+
+func selectJWKsKey(jwks *jose.JSONWebKeySet, kid string) (*jose.JSONWebKey, error) {
+	if kid != "" {
+		keys := jwks.Key(kid)
+		if len(keys) != 1 {
+			return nil, fmt.Errorf("could not match single key with id: %s", kid)
+		}
+		return &keys[0], nil
+	} else if len(jwks.Keys) == 0 {
+		return nil, errors.New("key set is empty")
+	} else {
+		return &jwks.Keys[0], nil
+	}
+}
+
+func selectJWKKey(jwk *jose.JSONWebKey, kid string) (*jose.JSONWebKey, error) {
+	if kid != "" && jwk.KeyID != "" && jwk.KeyID != kid {
+		return nil, fmt.Errorf("could not match key with id: %s", kid)
+	}
+	if jwk.KeyID == "" {
+		jwk.KeyID = kid
+	}
+	return jwk, nil
+}
+
+func encryptionKey(key *jose.JSONWebKey) *jose.JSONWebKey {
+	if _, ok := symmetricKey(key); ok {
+		return key
+	}
+	public := key.Public()
+	return &public
+}
+
+func certificateHeader(key *jose.JSONWebKey) []string {
+	x5c := make([]string, len(key.Certificates))
+	for i, cert := range key.Certificates {
+		x5c[i] = base64.StdEncoding.EncodeToString(cert.Raw)
+	}
+	return x5c
+}
@@ -1,72 +1,79 @@
 package jcrypt
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
-	"fmt"
-	"github.com/credding/crypt/pkg/flags"
-	"github.com/spf13/cobra"
 	"github.com/square/go-jose/v3"
-	"os"
 )
 
-var (
-	signKey = flags.FileRead()
-	signAlg string
-	signKid string
-)
-
-var signCommand = &cobra.Command{
-	Use:   "sign [key]",
-	Short: "Generate a JWS given a payload on stdin",
-	Args:  cobra.MaximumNArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		payload, err := decodePlainPayload(os.Stdin)
-		if err != nil {
-			return err
-		}
-		key, err := getKey(signKey.File(), args, signKid)
-
-		signer, err := getSigner(signAlg, key)
-		if err != nil {
-			return err
-		}
-		jws, err := signer.Sign(payload)
-		if err != nil {
-			return err
-		}
-		compact, err := jws.CompactSerialize()
-		if err != nil {
-			return err
-		}
-		_, err = fmt.Fprintln(os.Stdout, compact)
-		if err != nil {
-			return err
-		}
-
-		return nil
-	},
+// SignOptions configure Sign.
+type SignOptions struct {
+	// Algorithm is the signature algorithm. When empty it is taken from the
+	// key, or chosen from the key type.
+	Algorithm jose.SignatureAlgorithm
+	// Headers are additional protected headers.
+	Headers map[jose.HeaderKey]interface{}
+	// EmbedCertificates adds the key certificate chain, if any, as the x5c
+	// header unless Headers already sets one.
+	EmbedCertificates bool
+	// EmbedJWK adds the public key as the jwk header instead of the key ID
+	// as the kid header.
+	EmbedJWK bool
 }
 
-func init() {
-	signCommand.Flags().SortFlags = false
-	signCommand.Flags().VarP(signKey, "key", "k", "Key file")
-	signCommand.Flags().StringVarP(&signAlg, "alg", "a", "", "Signature algorithm (default auto)")
-	signCommand.Flags().StringVar(&signKid, "kid", "", "Key ID")
+// Sign signs payload with key, returning the JWS in compact serialization.
+func Sign(ctx context.Context, payload []byte, key *jose.JSONWebKey, opts *SignOptions) (string, error) {
+	if opts == nil {
+		opts = &SignOptions{}
+	}
+	err := checkContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := NewSigner(key, opts)
+	if err != nil {
+		return "", err
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return jws.CompactSerialize()
 }
 
-func getSigner(algArg string, key *jose.JSONWebKey) (jose.Signer, error) {
+// NewSigner returns a signer for key, validating symmetric key lengths
+// against the signature algorithm.
+func NewSigner(key *jose.JSONWebKey, opts *SignOptions) (jose.Signer, error) {
+	if opts == nil {
+		opts = &SignOptions{}
+	}
 	signingKey := jose.SigningKey{
-		Key: key,
-		Algorithm: getSignatureAlgorithm(algArg, key),
+		Key:       key,
+		Algorithm: opts.Algorithm,
+	}
+	if signingKey.Algorithm == "" {
+		signingKey.Algorithm = SignatureAlgorithm(key)
+	}
+	if bytes, ok := symmetricKey(key); ok {
+		err := checkSignatureKeySize(signingKey.Algorithm, bytes)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return jose.NewSigner(signingKey, nil)
-}
 
-func getSignatureAlgorithm(alg string, key *jose.JSONWebKey) jose.SignatureAlgorithm {
-	if alg != "" {
-		return jose.SignatureAlgorithm(alg)
+	headers := headerCopy(opts.Headers)
+	if _, ok := headers["x5c"]; !ok && opts.EmbedCertificates && len(key.Certificates) > 0 {
+		headers["x5c"] = certificateHeader(key)
 	}
+	return jose.NewSigner(signingKey, &jose.SignerOptions{ExtraHeaders: headers, EmbedJWK: opts.EmbedJWK})
+}
+
+// SignatureAlgorithm returns the signature algorithm of key, or the default
+// for its key type.
+func SignatureAlgorithm(key *jose.JSONWebKey) jose.SignatureAlgorithm {
 	if key.Algorithm != "" {
 		return jose.SignatureAlgorithm(key.Algorithm)
 	}
@@ -86,8 +93,10 @@ func defaultSignatureAlgorithm(key interface{}) jose.SignatureAlgorithm {
 		case "P-521":
 			return jose.ES512
 		}
+	case ed25519.PrivateKey:
+		return jose.EdDSA
 	case []byte:
-		return jose.HS256
+		return defaultSymmetricSignatureAlgorithm(key.([]byte))
 	}
 	return ""
 }
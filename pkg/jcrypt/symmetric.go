@@ -0,0 +1,90 @@
+package jcrypt
+
+import (
+	"fmt"
+	"github.com/square/go-jose/v3"
+)
+
+var signatureKeySizes = map[jose.SignatureAlgorithm]int{
+	jose.HS256: 32,
+	jose.HS384: 48,
+	jose.HS512: 64,
+}
+
+var keyWrapKeySizes = map[jose.KeyAlgorithm]int{
+	jose.A128KW:    16,
+	jose.A192KW:    24,
+	jose.A256KW:    32,
+	jose.A128GCMKW: 16,
+	jose.A192GCMKW: 24,
+	jose.A256GCMKW: 32,
+}
+
+var contentKeySizes = map[jose.ContentEncryption]int{
+	jose.A128CBC_HS256: 32,
+	jose.A192CBC_HS384: 48,
+	jose.A256CBC_HS512: 64,
+	jose.A128GCM:       16,
+	jose.A192GCM:       24,
+	jose.A256GCM:       32,
+}
+
+func symmetricKey(key *jose.JSONWebKey) ([]byte, bool) {
+	bytes, ok := key.Key.([]byte)
+	return bytes, ok
+}
+
+func defaultSymmetricSignatureAlgorithm(key []byte) jose.SignatureAlgorithm {
+	switch {
+	case len(key) >= signatureKeySizes[jose.HS512]:
+		return jose.HS512
+	case len(key) >= signatureKeySizes[jose.HS384]:
+		return jose.HS384
+	default:
+		return jose.HS256
+	}
+}
+
+func defaultSymmetricKeyAlgorithm(key []byte, enc jose.ContentEncryption) jose.KeyAlgorithm {
+	if len(key) == contentKeySizes[enc] {
+		return jose.DIRECT
+	}
+	for _, alg := range []jose.KeyAlgorithm{jose.A128KW, jose.A192KW, jose.A256KW} {
+		if len(key) == keyWrapKeySizes[alg] {
+			return alg
+		}
+	}
+	return jose.DIRECT
+}
+
+func checkSignatureKeySize(alg jose.SignatureAlgorithm, key []byte) error {
+	size, ok := signatureKeySizes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported signature algorithm for symmetric key: %s", alg)
+	}
+	if len(key) < size {
+		return fmt.Errorf("%s requires a key of at least %d bytes, got %d", alg, size, len(key))
+	}
+	return nil
+}
+
+func checkKeyAlgorithmKeySize(alg jose.KeyAlgorithm, enc jose.ContentEncryption, key []byte) error {
+	if alg == jose.DIRECT {
+		size, ok := contentKeySizes[enc]
+		if !ok {
+			return fmt.Errorf("unsupported encryption algorithm: %s", enc)
+		}
+		if len(key) != size {
+			return fmt.Errorf("%s with %s requires a key of %d bytes, got %d", alg, enc, size, len(key))
+		}
+		return nil
+	}
+	size, ok := keyWrapKeySizes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported key algorithm for symmetric key: %s", alg)
+	}
+	if len(key) != size {
+		return fmt.Errorf("%s requires a key of %d bytes, got %d", alg, size, len(key))
+	}
+	return nil
+}
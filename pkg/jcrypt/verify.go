@@ -1,56 +1,127 @@
 package jcrypt
 
 import (
+	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
-	"github.com/credding/crypt/pkg/flags"
-	"github.com/spf13/cobra"
 	"github.com/square/go-jose/v3"
-	"io/ioutil"
-	"os"
+	"time"
 )
 
-var (
-	verifyKey = flags.FileRead()
-	verifyKid string
-)
+// VerifyOptions configure Verify.
+type VerifyOptions struct {
+	// KeyID selects the verification key, defaulting to the kid header.
+	KeyID string
+}
 
-var verifyCommand = &cobra.Command{
-	Use:   "verify [key]",
-	Short: "Verity a JWS given on stdin",
-	Args:  cobra.MaximumNArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		compact, err := ioutil.ReadAll(os.Stdin)
-		if err != nil {
-			return err
-		}
-		jws, err := jose.ParseSigned(string(compact))
-		if err != nil {
-			return err
-		}
+// TrustOptions configure VerifyTrusted.
+type TrustOptions struct {
+	// Roots are the trusted root certificates.
+	Roots *x509.CertPool
+	// KeyUsages are the acceptable extended key usages of the signing
+	// certificate. When empty any usage is accepted.
+	KeyUsages []x509.ExtKeyUsage
+	// CRL, when set, is checked for revocation of the certificate it covers.
+	CRL *x509.RevocationList
+	// CurrentTime is the validation time, defaulting to now.
+	CurrentTime time.Time
+}
 
-		if verifyKid == "" {
-			verifyKid = jws.Signatures[0].Protected.KeyID
-		}
-		key, err := getKey(decryptKey.File(), args, verifyKid)
+// Verify verifies a compact JWS with key material as accepted by SelectKey,
+// returning the payload.
+func Verify(ctx context.Context, compact string, key interface{}, opts *VerifyOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &VerifyOptions{}
+	}
+	err := checkContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jws, err := jose.ParseSigned(compact)
+	if err != nil {
+		return nil, err
+	}
+	kid := opts.KeyID
+	if kid == "" {
+		kid = jws.Signatures[0].Protected.KeyID
+	}
+	jwk, err := SelectKey(key, kid)
+	if err != nil {
+		return nil, err
+	}
+	return jws.Verify(jwk)
+}
+
+// VerifyTrusted verifies a compact JWS using the leaf key of its x5c
+// certificate chain, after validating the chain to a trusted root. It returns
+// the payload and the validated chain.
+func VerifyTrusted(ctx context.Context, compact string, opts *TrustOptions) ([]byte, []*x509.Certificate, error) {
+	if opts == nil || opts.Roots == nil {
+		return nil, nil, errors.New("trusted roots not provided")
+	}
+	err := checkContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jws, err := jose.ParseSigned(compact)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyUsages := opts.KeyUsages
+	if len(keyUsages) == 0 {
+		keyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+	chains, err := jws.Signatures[0].Protected.Certificates(x509.VerifyOptions{
+		Roots:       opts.Roots,
+		KeyUsages:   keyUsages,
+		CurrentTime: opts.CurrentTime,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	chain := chains[0]
+
+	if opts.CRL != nil {
+		err = CheckRevocation(chain, opts.CRL, opts.CurrentTime)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
+	}
 
-		payload, err := jws.Verify(key)
-		if err != nil {
-			return err
+	payload, err := jws.Verify(chain[0].PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return payload, chain, nil
+}
+
+// CheckRevocation checks a validated certificate chain against a CRL issued
+// for one of its certificates, at the given time or now.
+func CheckRevocation(chain []*x509.Certificate, crl *x509.RevocationList, at time.Time) error {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if !crl.NextUpdate.IsZero() && at.After(crl.NextUpdate) {
+		return errors.New("crl has expired")
+	}
+	for i, cert := range chain[:len(chain)-1] {
+		issuer := chain[i+1]
+		if string(cert.RawIssuer) != string(crl.RawIssuer) {
+			continue
 		}
-		_, err = fmt.Fprintln(os.Stdout, payload)
+		err := crl.CheckSignatureFrom(issuer)
 		if err != nil {
-			return err
+			return fmt.Errorf("invalid crl signature: %w", err)
+		}
+		for _, revoked := range crl.RevokedCertificateEntries {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return fmt.Errorf("certificate revoked: %s", cert.Subject)
+			}
 		}
-
 		return nil
-	},
-}
-
-func init() {
-	verifyCommand.Flags().SortFlags = false
-	verifyCommand.Flags().VarP(verifyKey, "key", "k", "Key file")
-	verifyCommand.Flags().StringVar(&verifyKid, "kid", "", "Key ID")
+	}
+	return errors.New("crl issuer does not match the certificate chain")
 }
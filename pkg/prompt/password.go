@@ -0,0 +1,73 @@
+// Package prompt reads secrets from files, the environment, or the terminal.
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh/terminal"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Password reads a password from file if set, else from the environment
+// variable env if set, else by prompting on the terminal. With confirm, the
+// prompt asks for the password twice.
+func Password(file *os.File, env string, confirm bool) ([]byte, error) {
+	if file != nil {
+		defer file.Close()
+		password, err := ioutil.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		return checkPassword([]byte(strings.TrimRight(string(password), "\r\n")))
+	}
+	if env != "" {
+		password, ok := os.LookupEnv(env)
+		if !ok {
+			return nil, fmt.Errorf("environment variable not set: %s", env)
+		}
+		return checkPassword([]byte(password))
+	}
+	return promptPassword(confirm)
+}
+
+func promptPassword(confirm bool) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, errors.New("password not provided, and no terminal available to prompt")
+	}
+	defer tty.Close()
+
+	password, err := readTerminalPassword(tty, "Password: ")
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		confirmation, err := readTerminalPassword(tty, "Confirm password: ")
+		if err != nil {
+			return nil, err
+		}
+		if string(password) != string(confirmation) {
+			return nil, errors.New("passwords do not match")
+		}
+	}
+	return checkPassword(password)
+}
+
+func readTerminalPassword(tty *os.File, prompt string) ([]byte, error) {
+	_, err := fmt.Fprint(tty, prompt)
+	if err != nil {
+		return nil, err
+	}
+	password, err := terminal.ReadPassword(int(tty.Fd()))
+	_, _ = fmt.Fprintln(tty)
+	return password, err
+}
+
+func checkPassword(password []byte) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("password is empty")
+	}
+	return password, nil
+}
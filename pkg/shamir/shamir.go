@@ -0,0 +1,180 @@
+// Package shamir splits secrets into shares with Shamir's secret sharing
+// over GF(256), any threshold of which reconstruct the secret.
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// PEMType is the PEM block type of an encoded share.
+const PEMType = "SHAMIR SHARE"
+
+const (
+	idSize     = 8
+	digestSize = sha256.Size
+)
+
+var (
+	InvalidShare       = errors.New("invalid share")
+	InsufficientShares = errors.New("not enough shares to reconstruct the secret")
+	SecretMismatch     = errors.New("reconstructed secret failed its integrity check")
+)
+
+// Share is one share of a split secret.
+type Share struct {
+	ID        []byte
+	Index     byte
+	Threshold int
+	Total     int
+	Data      []byte
+}
+
+// Split splits secret into total shares, any threshold of which reconstruct
+// it. A digest of the secret is split alongside it, so Combine can detect a
+// wrong reconstruction without the digest being revealed by any share.
+func Split(secret []byte, total int, threshold int) ([]*Share, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret is empty")
+	}
+	if threshold < 2 || threshold > total || total > 255 {
+		return nil, fmt.Errorf("invalid threshold %d of %d: need 2 <= threshold <= shares <= 255", threshold, total)
+	}
+	id := make([]byte, idSize)
+	_, err := rand.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(secret)
+	payload := append(append([]byte{}, secret...), digest[:]...)
+
+	shares := make([]*Share, total)
+	for i := range shares {
+		shares[i] = &Share{
+			ID:        id,
+			Index:     byte(i + 1),
+			Threshold: threshold,
+			Total:     total,
+			Data:      make([]byte, len(payload)),
+		}
+	}
+	coefficients := make([]byte, threshold)
+	for pos, value := range payload {
+		_, err = rand.Read(coefficients[1:])
+		if err != nil {
+			return nil, err
+		}
+		coefficients[0] = value
+		for _, share := range shares {
+			share.Data[pos] = evaluate(coefficients, share.Index)
+		}
+	}
+	return shares, nil
+}
+
+// Combine reconstructs a secret from at least threshold shares of the same
+// split.
+func Combine(shares []*Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, InsufficientShares
+	}
+	first := shares[0]
+	seen := make(map[byte]bool)
+	xs := make([]byte, 0, len(shares))
+	for _, share := range shares {
+		if !bytes.Equal(share.ID, first.ID) || share.Threshold != first.Threshold || len(share.Data) != len(first.Data) {
+			return nil, errors.New("shares are from different splits")
+		}
+		if share.Index == 0 || seen[share.Index] {
+			continue
+		}
+		seen[share.Index] = true
+		xs = append(xs, share.Index)
+	}
+	if len(xs) < first.Threshold || len(first.Data) <= digestSize {
+		return nil, InsufficientShares
+	}
+	xs = xs[:first.Threshold]
+
+	payload := make([]byte, len(first.Data))
+	ys := make([]byte, len(xs))
+	for pos := range payload {
+		for i, x := range xs {
+			for _, share := range shares {
+				if share.Index == x {
+					ys[i] = share.Data[pos]
+					break
+				}
+			}
+		}
+		payload[pos] = interpolate(xs, ys)
+	}
+	secret := payload[:len(payload)-digestSize]
+	digest := sha256.Sum256(secret)
+	if !bytes.Equal(digest[:], payload[len(secret):]) {
+		return nil, SecretMismatch
+	}
+	return secret, nil
+}
+
+// EncodePEM encodes the share as a labelled PEM block, with a checksum over
+// its headers and data to catch transcription errors.
+func (s *Share) EncodePEM() *pem.Block {
+	return &pem.Block{
+		Type: PEMType,
+		Headers: map[string]string{
+			"Split-ID":  hex.EncodeToString(s.ID),
+			"Share":     fmt.Sprintf("%d of %d", s.Index, s.Total),
+			"Threshold": strconv.Itoa(s.Threshold),
+			"Checksum":  hex.EncodeToString(s.checksum()),
+		},
+		Bytes: s.Data,
+	}
+}
+
+// ParsePEM decodes a share from a PEM block, verifying its checksum.
+func ParsePEM(block *pem.Block) (*Share, error) {
+	if block.Type != PEMType {
+		return nil, fmt.Errorf("unexpected PEM type: %s", block.Type)
+	}
+	var index, total int
+	_, err := fmt.Sscanf(block.Headers["Share"], "%d of %d", &index, &total)
+	if err != nil || index < 1 || index > 255 || total < index {
+		return nil, InvalidShare
+	}
+	threshold, err := strconv.Atoi(block.Headers["Threshold"])
+	if err != nil || threshold < 2 || threshold > total {
+		return nil, InvalidShare
+	}
+	id, err := hex.DecodeString(block.Headers["Split-ID"])
+	if err != nil || len(id) != idSize {
+		return nil, InvalidShare
+	}
+	share := &Share{
+		ID:        id,
+		Index:     byte(index),
+		Threshold: threshold,
+		Total:     total,
+		Data:      block.Bytes,
+	}
+	checksum, err := hex.DecodeString(block.Headers["Checksum"])
+	if err != nil || !bytes.Equal(checksum, share.checksum()) {
+		return nil, fmt.Errorf("share %d failed its checksum", index)
+	}
+	return share, nil
+}
+
+func (s *Share) checksum() []byte {
+	h := sha256.New()
+	h.Write(s.ID)
+	_ = binary.Write(h, binary.BigEndian, []uint16{uint16(s.Index), uint16(s.Threshold), uint16(s.Total)})
+	h.Write(s.Data)
+	return h.Sum(nil)[:8]
+}
@@ -0,0 +1,129 @@
+package spiffe
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/square/go-jose/v3"
+	"time"
+)
+
+const (
+	// UseX509SVID is the use of bundle keys that are X.509-SVID authorities.
+	UseX509SVID = "x509-svid"
+	// UseJWTSVID is the use of bundle keys that are JWT-SVID authorities.
+	UseJWTSVID = "jwt-svid"
+)
+
+// Bundle is the trust bundle of a trust domain.
+type Bundle struct {
+	TrustDomain string
+	// X509Authorities are the root certificates of X.509-SVIDs.
+	X509Authorities []*x509.Certificate
+	// JWTAuthorities are the public keys of JWT-SVIDs, with key IDs.
+	JWTAuthorities []jose.JSONWebKey
+	SequenceNumber uint64
+	RefreshHint    time.Duration
+}
+
+type bundleDocument struct {
+	Keys           []jose.JSONWebKey `json:"keys"`
+	SequenceNumber uint64            `json:"spiffe_sequence,omitempty"`
+	RefreshHint    int64             `json:"spiffe_refresh_hint,omitempty"`
+}
+
+// ParseBundle parses the JWK set form of the trust bundle of trustDomain.
+// Keys with other uses are ignored.
+func ParseBundle(trustDomain string, data []byte) (*Bundle, error) {
+	trustDomain, err := ParseTrustDomain(trustDomain)
+	if err != nil {
+		return nil, err
+	}
+	document := &bundleDocument{}
+	err = json.Unmarshal(data, document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust bundle: %w", err)
+	}
+	if document.Keys == nil {
+		return nil, errors.New("invalid trust bundle: no keys member")
+	}
+	bundle := &Bundle{
+		TrustDomain:    trustDomain,
+		SequenceNumber: document.SequenceNumber,
+		RefreshHint:    time.Duration(document.RefreshHint) * time.Second,
+	}
+	for i, key := range document.Keys {
+		err = bundle.addKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trust bundle key %d: %w", i, err)
+		}
+	}
+	return bundle, nil
+}
+
+func (b *Bundle) addKey(key jose.JSONWebKey) error {
+	switch key.Use {
+	case UseX509SVID:
+		if len(key.Certificates) != 1 {
+			return errors.New("x509-svid key must have exactly one certificate")
+		}
+		b.X509Authorities = append(b.X509Authorities, key.Certificates[0])
+	case UseJWTSVID:
+		if key.KeyID == "" {
+			return errors.New("jwt-svid key has no key ID")
+		}
+		if !key.IsPublic() {
+			return errors.New("jwt-svid key is not a public key")
+		}
+		b.JWTAuthorities = append(b.JWTAuthorities, key)
+	}
+	return nil
+}
+
+// Marshal returns the JWK set form of the bundle.
+func (b *Bundle) Marshal() ([]byte, error) {
+	document := &bundleDocument{
+		Keys:           []jose.JSONWebKey{},
+		SequenceNumber: b.SequenceNumber,
+		RefreshHint:    int64(b.RefreshHint / time.Second),
+	}
+	for _, cert := range b.X509Authorities {
+		document.Keys = append(document.Keys, jose.JSONWebKey{
+			Key:          cert.PublicKey,
+			Certificates: []*x509.Certificate{cert},
+			Use:          UseX509SVID,
+		})
+	}
+	for _, key := range b.JWTAuthorities {
+		if key.KeyID == "" {
+			return nil, errors.New("jwt-svid authority has no key ID")
+		}
+		public := key.Public()
+		if public.Key == nil {
+			return nil, errors.New("jwt-svid authority is not an asymmetric key")
+		}
+		public.Use = UseJWTSVID
+		public.Certificates = nil
+		document.Keys = append(document.Keys, public)
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// JWTAuthority returns the JWT-SVID authority with a key ID.
+func (b *Bundle) JWTAuthority(kid string) (*jose.JSONWebKey, bool) {
+	for i := range b.JWTAuthorities {
+		if b.JWTAuthorities[i].KeyID == kid {
+			return &b.JWTAuthorities[i], true
+		}
+	}
+	return nil, false
+}
+
+func (b *Bundle) x509Roots() *x509.CertPool {
+	roots := x509.NewCertPool()
+	for _, cert := range b.X509Authorities {
+		roots.AddCert(cert)
+	}
+	return roots
+}
@@ -0,0 +1,131 @@
+package spiffe
+
+import (
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/jcrypt"
+	"github.com/square/go-jose/v3"
+	"github.com/square/go-jose/v3/jwt"
+	"net/url"
+	"time"
+)
+
+// DefaultJWTSVIDValidity is the validity of JWT-SVIDs issued without an
+// explicit expiry.
+const DefaultJWTSVIDValidity = 5 * time.Minute
+
+// jwtSVIDAlgorithms are the signature algorithms JWT-SVIDs may use.
+var jwtSVIDAlgorithms = map[string]bool{
+	string(jose.RS256): true,
+	string(jose.RS384): true,
+	string(jose.RS512): true,
+	string(jose.ES256): true,
+	string(jose.ES384): true,
+	string(jose.ES512): true,
+	string(jose.PS256): true,
+	string(jose.PS384): true,
+	string(jose.PS512): true,
+}
+
+// JWTSVID is a validated JWT-SVID.
+type JWTSVID struct {
+	ID       *url.URL
+	Audience []string
+	Expiry   time.Time
+	// Claims are all the claims of the token.
+	Claims map[string]interface{}
+}
+
+// IssueJWTSVID issues a JWT-SVID for id and audience, signed with a private
+// key that has a key ID. The expiry defaults to DefaultJWTSVIDValidity from
+// now.
+func IssueJWTSVID(id *url.URL, audience []string, key *jose.JSONWebKey, expiry time.Time) (string, error) {
+	_, err := ParseID(id.String())
+	if err != nil {
+		return "", err
+	}
+	if len(audience) == 0 {
+		return "", errors.New("audience not provided")
+	}
+	if key.KeyID == "" {
+		return "", errors.New("JWT-SVID signing key has no key ID")
+	}
+	algorithm := jcrypt.SignatureAlgorithm(key)
+	if !jwtSVIDAlgorithms[string(algorithm)] {
+		return "", fmt.Errorf("unsupported JWT-SVID signature algorithm: %q", algorithm)
+	}
+	now := time.Now()
+	if expiry.IsZero() {
+		expiry = now.Add(DefaultJWTSVIDValidity)
+	}
+
+	signer, err := jcrypt.NewSigner(key, &jcrypt.SignOptions{
+		Algorithm: algorithm,
+		Headers:   map[jose.HeaderKey]interface{}{jose.HeaderType: "JWT"},
+	})
+	if err != nil {
+		return "", err
+	}
+	return jwt.Signed(signer).Claims(&jwt.Claims{
+		Subject:  id.String(),
+		Audience: audience,
+		Expiry:   jwt.NewNumericDate(expiry),
+		IssuedAt: jwt.NewNumericDate(now),
+	}).CompactSerialize()
+}
+
+// ValidateJWTSVID validates a JWT-SVID against the JWT authorities of a
+// bundle, and checks that it is intended for audience.
+func ValidateJWTSVID(token string, bundle *Bundle, audience string) (*JWTSVID, error) {
+	if audience == "" {
+		return nil, errors.New("audience not provided")
+	}
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, err
+	}
+	header := parsed.Headers[0]
+	if !jwtSVIDAlgorithms[header.Algorithm] {
+		return nil, fmt.Errorf("unsupported JWT-SVID signature algorithm: %q", header.Algorithm)
+	}
+	if typ, ok := header.ExtraHeaders[jose.HeaderType]; ok && typ != "JWT" && typ != "JOSE" {
+		return nil, fmt.Errorf("unsupported JWT-SVID type: %v", typ)
+	}
+	if header.KeyID == "" {
+		return nil, errors.New("JWT-SVID has no key ID")
+	}
+	key, ok := bundle.JWTAuthority(header.KeyID)
+	if !ok {
+		return nil, fmt.Errorf("no JWT authority with key ID %q in trust domain %s", header.KeyID, bundle.TrustDomain)
+	}
+
+	claims := &jwt.Claims{}
+	all := map[string]interface{}{}
+	err = parsed.Claims(key, claims, &all)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("JWT-SVID has no subject")
+	}
+	id, err := ParseID(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if id.Host != bundle.TrustDomain {
+		return nil, fmt.Errorf("SPIFFE ID %s is not in trust domain %s", id, bundle.TrustDomain)
+	}
+	if claims.Expiry == nil {
+		return nil, errors.New("JWT-SVID has no expiry")
+	}
+	err = claims.Validate(jwt.Expected{Audience: jwt.Audience{audience}, Time: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	return &JWTSVID{
+		ID:       id,
+		Audience: claims.Audience,
+		Expiry:   claims.Expiry.Time(),
+		Claims:   all,
+	}, nil
+}
@@ -0,0 +1,97 @@
+// Package spiffe issues and validates SPIFFE X.509 and JWT SVIDs, and reads
+// and writes SPIFFE trust bundles.
+package spiffe
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	scheme      = "spiffe"
+	maxIDLength = 2048
+)
+
+// ParseID parses a SPIFFE ID of the form spiffe://trust-domain/path.
+func ParseID(id string) (*url.URL, error) {
+	err := checkID(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SPIFFE ID %q: %w", id, err)
+	}
+	return url.Parse(id)
+}
+
+// ParseTrustDomain parses a trust domain name, given alone or as a
+// spiffe://trust-domain URI.
+func ParseTrustDomain(trustDomain string) (string, error) {
+	name := strings.TrimPrefix(trustDomain, scheme+"://")
+	err := checkTrustDomain(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid trust domain %q: %w", trustDomain, err)
+	}
+	return name, nil
+}
+
+// TrustDomainID returns the SPIFFE ID of a trust domain.
+func TrustDomainID(trustDomain string) *url.URL {
+	return &url.URL{Scheme: scheme, Host: trustDomain}
+}
+
+func checkID(id string) error {
+	if len(id) > maxIDLength {
+		return errors.New("longer than 2048 bytes")
+	}
+	if !strings.HasPrefix(id, scheme+"://") {
+		return errors.New("scheme is not spiffe")
+	}
+	rest := id[len(scheme+"://"):]
+	path := ""
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		rest, path = rest[:i], rest[i:]
+	}
+	err := checkTrustDomain(rest)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return nil
+	}
+	for _, segment := range strings.Split(path[1:], "/") {
+		switch segment {
+		case "":
+			return errors.New("path contains an empty segment")
+		case ".", "..":
+			return errors.New("path contains a relative segment")
+		}
+		for _, c := range segment {
+			if !isIDChar(c, true) {
+				return fmt.Errorf("path contains %q", c)
+			}
+		}
+	}
+	return nil
+}
+
+func checkTrustDomain(name string) error {
+	if name == "" {
+		return errors.New("trust domain is empty")
+	}
+	for _, c := range name {
+		if !isIDChar(c, false) {
+			return fmt.Errorf("trust domain contains %q", c)
+		}
+	}
+	return nil
+}
+
+func isIDChar(c rune, upper bool) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '.', c == '-', c == '_':
+		return true
+	case c >= 'A' && c <= 'Z':
+		return upper
+	}
+	return false
+}
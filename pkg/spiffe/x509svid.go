@@ -0,0 +1,112 @@
+package spiffe
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/credding/crypt/pkg/crypt"
+	"net/url"
+	"time"
+)
+
+// DefaultX509SVIDValidity is the validity of X.509-SVIDs issued without an
+// explicit expiry.
+const DefaultX509SVIDValidity = time.Hour
+
+// IssueX509SVID issues an X.509-SVID for id and publicKey from a CA. The
+// subject is empty and id is the only URI SAN. The expiry defaults to
+// DefaultX509SVIDValidity from now, ending no later than the issuer's.
+func IssueX509SVID(id *url.URL, publicKey crypto.PublicKey, issuer *crypt.Issuer, notAfter time.Time) (*x509.Certificate, error) {
+	if issuer == nil || issuer.Certificate == nil || issuer.Key == nil {
+		return nil, errors.New("issuer certificate and key not provided")
+	}
+	if !issuer.Certificate.IsCA {
+		return nil, errors.New("issuer is not a CA")
+	}
+	_, err := ParseID(id.String())
+	if err != nil {
+		return nil, err
+	}
+	serialNumber, err := crypt.SerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	notBefore := time.Now()
+	if notAfter.IsZero() {
+		notAfter = notBefore.Add(DefaultX509SVIDValidity)
+	}
+	if issuer.Certificate.NotAfter.Before(notAfter) {
+		notAfter = issuer.Certificate.NotAfter
+	}
+
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := publicKey.(*rsa.PublicKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		URIs:                  []*url.URL{id},
+		BasicConstraintsValid: true,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	certRaw, err := x509.CreateCertificate(rand.Reader, template, issuer.Certificate, publicKey, issuer.Key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(certRaw)
+}
+
+// ValidateX509SVID validates an X.509-SVID and its intermediates against the
+// X.509 authorities of a bundle, returning the SPIFFE ID and the verified
+// chains.
+func ValidateX509SVID(chain []*x509.Certificate, bundle *Bundle) (*url.URL, [][]*x509.Certificate, error) {
+	if len(chain) == 0 {
+		return nil, nil, errors.New("no certificates provided")
+	}
+	leaf := chain[0]
+	id, err := x509SVIDID(leaf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if id.Host != bundle.TrustDomain {
+		return nil, nil, fmt.Errorf("SPIFFE ID %s is not in trust domain %s", id, bundle.TrustDomain)
+	}
+	if leaf.IsCA {
+		return nil, nil, errors.New("X.509-SVID is a CA certificate")
+	}
+	if leaf.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return nil, nil, errors.New("X.509-SVID lacks the digitalSignature key usage")
+	}
+	if leaf.KeyUsage&(x509.KeyUsageCertSign|x509.KeyUsageCRLSign) != 0 {
+		return nil, nil, errors.New("X.509-SVID has a CA key usage")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		if !cert.IsCA || cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+			return nil, nil, fmt.Errorf("intermediate %s is not a signing certificate", cert.Subject)
+		}
+		intermediates.AddCert(cert)
+	}
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         bundle.x509Roots(),
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return id, chains, nil
+}
+
+func x509SVIDID(cert *x509.Certificate) (*url.URL, error) {
+	if len(cert.URIs) != 1 {
+		return nil, fmt.Errorf("X.509-SVID must have exactly one URI SAN, has %d", len(cert.URIs))
+	}
+	return ParseID(cert.URIs[0].String())
+}
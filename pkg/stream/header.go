@@ -0,0 +1,123 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	stanzaPrefix = "->"
+	macPrefix    = "---"
+
+	maxHeaderLine = 4096
+	maxStanzas    = 1024
+)
+
+func writeHeader(out io.Writer, stanzas []*stanza, fileKey []byte) error {
+	header := &bytes.Buffer{}
+	header.WriteString(version)
+	header.WriteString("\n")
+	for _, s := range stanzas {
+		header.WriteString(stanzaPrefix)
+		header.WriteString(" ")
+		header.WriteString(s.Type)
+		for _, arg := range s.Args {
+			header.WriteString(" ")
+			header.WriteString(arg)
+		}
+		header.WriteString("\n")
+	}
+	header.WriteString(macPrefix)
+
+	mac, err := headerMAC(fileKey, header.Bytes())
+	if err != nil {
+		return err
+	}
+	header.WriteString(" ")
+	header.WriteString(encode(mac))
+	header.WriteString("\n")
+
+	_, err = out.Write(header.Bytes())
+	return err
+}
+
+type header struct {
+	stanzas []*stanza
+	raw     []byte
+	mac     []byte
+}
+
+func readHeader(in *bufio.Reader) (*header, error) {
+	h := &header{}
+	raw := &bytes.Buffer{}
+
+	line, err := readHeaderLine(in)
+	if err != nil {
+		return nil, err
+	}
+	if line != version {
+		return nil, fmt.Errorf("%w: unsupported version", InvalidHeader)
+	}
+	raw.WriteString(line + "\n")
+
+	for {
+		line, err := readHeaderLine(in)
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Split(line, " ")
+		switch fields[0] {
+		case stanzaPrefix:
+			if len(fields) < 2 || len(h.stanzas) == maxStanzas {
+				return nil, InvalidHeader
+			}
+			h.stanzas = append(h.stanzas, &stanza{Type: fields[1], Args: fields[2:]})
+			raw.WriteString(line + "\n")
+		case macPrefix:
+			if len(fields) != 2 {
+				return nil, InvalidHeader
+			}
+			h.mac, err = decode(fields[1])
+			if err != nil {
+				return nil, InvalidHeader
+			}
+			raw.WriteString(macPrefix)
+			h.raw = raw.Bytes()
+			return h, nil
+		default:
+			return nil, InvalidHeader
+		}
+	}
+}
+
+func readHeaderLine(in *bufio.Reader) (string, error) {
+	line := &bytes.Buffer{}
+	for {
+		b, err := in.ReadByte()
+		if err == io.EOF {
+			return "", fmt.Errorf("%w: unexpected end of header", InvalidHeader)
+		}
+		if err != nil {
+			return "", err
+		}
+		if b == '\n' {
+			return line.String(), nil
+		}
+		if line.Len() == maxHeaderLine {
+			return "", fmt.Errorf("%w: header line too long", InvalidHeader)
+		}
+		line.WriteByte(b)
+	}
+}
+
+func (h *header) verify(fileKey []byte) bool {
+	mac, err := headerMAC(fileKey, h.raw)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(mac, h.mac)
+}
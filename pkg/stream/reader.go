@@ -0,0 +1,118 @@
+package stream
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/cipher"
+	"fmt"
+	"io"
+)
+
+type reader struct {
+	in      *bufio.Reader
+	aead    cipher.AEAD
+	nonce   []byte
+	counter uint64
+	chunk   []byte
+	plain   []byte
+	done    bool
+}
+
+// Decrypt reads a stream header from in, unwraps the file key with the first
+// matching identity, and returns a reader of the decrypted payload. Reads
+// fail with InvalidPayload if the payload was truncated or tampered with.
+//
+// Identities are RSA, ECDSA, or X25519 private keys.
+func Decrypt(in io.Reader, identities []crypto.PrivateKey) (io.Reader, error) {
+	buffered := bufio.NewReaderSize(in, encChunkSize)
+	h, err := readHeader(buffered)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := unwrapHeader(h, identities)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	_, err = io.ReadFull(buffered, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing nonce", InvalidPayload)
+	}
+	aead, err := payloadAEAD(fileKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reader{
+		in:    buffered,
+		aead:  aead,
+		nonce: make([]byte, chunkNonceLen),
+		chunk: make([]byte, encChunkSize),
+	}, nil
+}
+
+func unwrapHeader(h *header, identities []crypto.PrivateKey) ([]byte, error) {
+	for _, identity := range identities {
+		for _, s := range h.stanzas {
+			fileKey, ok := unwrapFileKey(identity, s)
+			if !ok {
+				continue
+			}
+			if !h.verify(fileKey) {
+				return nil, fmt.Errorf("%w: mac mismatch", InvalidHeader)
+			}
+			return fileKey, nil
+		}
+	}
+	return nil, NoIdentityMatched
+}
+
+func (r *reader) Read(data []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		err := r.next()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(data, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *reader) next() error {
+	n, err := io.ReadFull(r.in, r.chunk)
+	switch {
+	case err == io.EOF:
+		return fmt.Errorf("%w: stream truncated", InvalidPayload)
+	case err == io.ErrUnexpectedEOF:
+	case err != nil:
+		return err
+	}
+
+	final := n < encChunkSize
+	if !final {
+		_, err := r.in.Peek(1)
+		if err == io.EOF {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	chunkNonce(r.nonce, r.counter, final)
+	plain, err := r.aead.Open(r.chunk[:0], r.nonce, r.chunk[:n], nil)
+	if err != nil {
+		return fmt.Errorf("%w: chunk %d failed authentication", InvalidPayload, r.counter)
+	}
+	if len(plain) == 0 && r.counter > 0 {
+		return fmt.Errorf("%w: unexpected empty final chunk", InvalidPayload)
+	}
+	r.plain = plain
+	r.counter++
+	r.done = final
+	return nil
+}
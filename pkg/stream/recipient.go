@@ -0,0 +1,151 @@
+package stream
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+)
+
+const (
+	rsaStanza  = "rsa-oaep-sha256"
+	ecdhStanza = "ecdh"
+)
+
+var curveNames = map[ecdh.Curve]string{
+	ecdh.P256():   "p-256",
+	ecdh.P384():   "p-384",
+	ecdh.P521():   "p-521",
+	ecdh.X25519(): "x25519",
+}
+
+type stanza struct {
+	Type string
+	Args []string
+}
+
+func wrapFileKey(recipient crypto.PublicKey, fileKey []byte) (*stanza, error) {
+	switch recipient.(type) {
+	case *rsa.PublicKey:
+		wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, recipient.(*rsa.PublicKey), fileKey, []byte(version))
+		if err != nil {
+			return nil, err
+		}
+		return &stanza{Type: rsaStanza, Args: []string{encode(wrapped)}}, nil
+	case *ecdsa.PublicKey:
+		public, err := recipient.(*ecdsa.PublicKey).ECDH()
+		if err != nil {
+			return nil, err
+		}
+		return wrapFileKeyECDH(public, fileKey)
+	case *ecdh.PublicKey:
+		return wrapFileKeyECDH(recipient.(*ecdh.PublicKey), fileKey)
+	default:
+		return nil, fmt.Errorf("unsupported recipient key type: %v", reflect.TypeOf(recipient))
+	}
+}
+
+func wrapFileKeyECDH(recipient *ecdh.PublicKey, fileKey []byte) (*stanza, error) {
+	curveName, ok := curveNames[recipient.Curve()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported recipient curve: %v", recipient.Curve())
+	}
+	ephemeral, err := recipient.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := ecdhWrapAEAD(shared, ephemeral.PublicKey(), recipient, curveName)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := aead.Seal(nil, make([]byte, aead.NonceSize()), fileKey, nil)
+	return &stanza{
+		Type: ecdhStanza,
+		Args: []string{curveName, encode(ephemeral.PublicKey().Bytes()), encode(wrapped)},
+	}, nil
+}
+
+func unwrapFileKey(identity crypto.PrivateKey, s *stanza) ([]byte, bool) {
+	switch identity.(type) {
+	case *rsa.PrivateKey:
+		if s.Type != rsaStanza || len(s.Args) != 1 {
+			return nil, false
+		}
+		wrapped, err := decode(s.Args[0])
+		if err != nil {
+			return nil, false
+		}
+		fileKey, err := rsa.DecryptOAEP(sha256.New(), nil, identity.(*rsa.PrivateKey), wrapped, []byte(version))
+		return fileKey, err == nil
+	case *ecdsa.PrivateKey:
+		private, err := identity.(*ecdsa.PrivateKey).ECDH()
+		if err != nil {
+			return nil, false
+		}
+		return unwrapFileKeyECDH(private, s)
+	case *ecdh.PrivateKey:
+		return unwrapFileKeyECDH(identity.(*ecdh.PrivateKey), s)
+	default:
+		return nil, false
+	}
+}
+
+func unwrapFileKeyECDH(identity *ecdh.PrivateKey, s *stanza) ([]byte, bool) {
+	if s.Type != ecdhStanza || len(s.Args) != 3 || s.Args[0] != curveNames[identity.Curve()] {
+		return nil, false
+	}
+	ephemeralBytes, err := decode(s.Args[1])
+	if err != nil {
+		return nil, false
+	}
+	ephemeral, err := identity.Curve().NewPublicKey(ephemeralBytes)
+	if err != nil {
+		return nil, false
+	}
+	wrapped, err := decode(s.Args[2])
+	if err != nil {
+		return nil, false
+	}
+	shared, err := identity.ECDH(ephemeral)
+	if err != nil {
+		return nil, false
+	}
+	aead, err := ecdhWrapAEAD(shared, ephemeral, identity.PublicKey(), s.Args[0])
+	if err != nil {
+		return nil, false
+	}
+	fileKey, err := aead.Open(nil, make([]byte, aead.NonceSize()), wrapped, nil)
+	return fileKey, err == nil
+}
+
+func ecdhWrapAEAD(shared []byte, ephemeral *ecdh.PublicKey, recipient *ecdh.PublicKey, curveName string) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, ephemeral.Bytes()...), recipient.Bytes()...)
+	key, err := deriveKey(shared, salt, version+" "+ecdhStanza+" "+curveName)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encode(data []byte) string {
+	return base64.RawStdEncoding.EncodeToString(data)
+}
+
+func decode(data string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(data)
+}
@@ -0,0 +1,233 @@
+package stream
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+type testIdentity struct {
+	name    string
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+func newTestIdentities(t *testing.T) []testIdentity {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []testIdentity{
+		{"rsa", rsaKey, &rsaKey.PublicKey},
+		{"ecdsa", ecdsaKey, &ecdsaKey.PublicKey},
+		{"x25519", x25519Key, x25519Key.PublicKey()},
+	}
+}
+
+func encryptBytes(t *testing.T, plaintext []byte, recipients ...crypto.PublicKey) []byte {
+	t.Helper()
+	out := &bytes.Buffer{}
+	writer, err := Encrypt(out, recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = writer.Write(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func decryptBytes(ciphertext []byte, identities ...crypto.PrivateKey) ([]byte, error) {
+	reader, err := Decrypt(bytes.NewReader(ciphertext), identities)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// splitStream returns the header, payload nonce, and sealed chunks of a
+// stream.
+func splitStream(t *testing.T, ciphertext []byte) ([]byte, []byte, [][]byte) {
+	t.Helper()
+	end := bytes.Index(ciphertext, []byte("\n"+macPrefix+" "))
+	if end < 0 {
+		t.Fatal("no header mac line")
+	}
+	end += bytes.IndexByte(ciphertext[end+1:], '\n') + 2
+	header := ciphertext[:end]
+	nonce := ciphertext[end : end+nonceSize]
+	var chunks [][]byte
+	for payload := ciphertext[end+nonceSize:]; len(payload) > 0; {
+		n := encChunkSize
+		if len(payload) < n {
+			n = len(payload)
+		}
+		chunks = append(chunks, payload[:n])
+		payload = payload[n:]
+	}
+	return header, nonce, chunks
+}
+
+func joinStream(header []byte, nonce []byte, chunks ...[]byte) []byte {
+	return bytes.Join(append([][]byte{header, nonce}, chunks...), nil)
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	data := make([]byte, n)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRoundTrip(t *testing.T) {
+	identities := newTestIdentities(t)
+	sizes := []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 5}
+	for _, identity := range identities {
+		for _, size := range sizes {
+			t.Run(fmt.Sprintf("%s/%d", identity.name, size), func(t *testing.T) {
+				plaintext := randomBytes(t, size)
+				decrypted, err := decryptBytes(encryptBytes(t, plaintext, identity.public), identity.private)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(decrypted, plaintext) {
+					t.Error("decrypted payload does not match")
+				}
+			})
+		}
+	}
+}
+
+func TestMultipleRecipients(t *testing.T) {
+	identities := newTestIdentities(t)
+	plaintext := randomBytes(t, 1000)
+	ciphertext := encryptBytes(t, plaintext, identities[0].public, identities[1].public, identities[2].public)
+	for _, identity := range identities {
+		decrypted, err := decryptBytes(ciphertext, identity.private)
+		if err != nil {
+			t.Fatalf("%s: %v", identity.name, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("%s: decrypted payload does not match", identity.name)
+		}
+	}
+
+	other, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = decryptBytes(ciphertext, other)
+	if err != NoIdentityMatched {
+		t.Errorf("Decrypt with another key: %v, want NoIdentityMatched", err)
+	}
+}
+
+func TestTamperedPayload(t *testing.T) {
+	identity := newTestIdentities(t)[2]
+	ciphertext := encryptBytes(t, randomBytes(t, 3*chunkSize+5), identity.public)
+	header, nonce, chunks := splitStream(t, ciphertext)
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, want 4", len(chunks))
+	}
+	flipped := append([]byte{}, chunks[1]...)
+	flipped[10] ^= 1
+	otherNonce := append([]byte{}, nonce...)
+	otherNonce[0] ^= 1
+
+	tests := []struct {
+		name       string
+		ciphertext []byte
+	}{
+		{"final chunk dropped", joinStream(header, nonce, chunks[:3]...)},
+		{"truncated mid-chunk", ciphertext[:len(ciphertext)-3]},
+		{"payload dropped", joinStream(header, nonce)},
+		{"chunks reordered", joinStream(header, nonce, chunks[0], chunks[2], chunks[1], chunks[3])},
+		{"chunk repeated", joinStream(header, nonce, chunks[0], chunks[0], chunks[1], chunks[2], chunks[3])},
+		{"chunk modified", joinStream(header, nonce, chunks[0], flipped, chunks[2], chunks[3])},
+		{"payload nonce modified", joinStream(header, otherNonce, chunks...)},
+		{"data appended", append(append([]byte{}, ciphertext...), 0)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decryptBytes(test.ciphertext, identity.private)
+			if !errors.Is(err, InvalidPayload) {
+				t.Errorf("Decrypt: %v, want InvalidPayload", err)
+			}
+		})
+	}
+}
+
+func TestTamperedHeader(t *testing.T) {
+	identities := newTestIdentities(t)
+	identity := identities[1]
+	ciphertext := encryptBytes(t, randomBytes(t, 100), identity.public)
+	header, nonce, chunks := splitStream(t, ciphertext)
+	macLine := bytes.LastIndex(header, []byte(macPrefix+" "))
+
+	// A stanza for another recipient spliced in from a second stream.
+	otherHeader, _, _ := splitStream(t, encryptBytes(t, nil, identities[2].public))
+	otherStanza := otherHeader[len(version)+1 : bytes.LastIndex(otherHeader, []byte(macPrefix+" "))]
+	added := bytes.Join([][]byte{header[:macLine], otherStanza, header[macLine:]}, nil)
+
+	flippedMAC := append([]byte{}, header...)
+	flippedMAC[macLine+len(macPrefix)+2] ^= 1
+
+	tests := []struct {
+		name   string
+		header []byte
+	}{
+		{"stanza added", added},
+		{"mac modified", flippedMAC},
+		{"version changed", append([]byte("crypt-stream/v2"), header[len(version):]...)},
+		{"mac line missing", header[:macLine]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decryptBytes(joinStream(test.header, nonce, chunks...), identity.private)
+			if !errors.Is(err, InvalidHeader) {
+				t.Errorf("Decrypt: %v, want InvalidHeader", err)
+			}
+		})
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	identity := newTestIdentities(t)[2]
+	writer, err := Encrypt(&bytes.Buffer{}, []crypto.PublicKey{identity.public})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = writer.Write([]byte("late"))
+	if err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
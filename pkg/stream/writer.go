@@ -0,0 +1,133 @@
+package stream
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+type writer struct {
+	out     io.Writer
+	aead    cipher.AEAD
+	nonce   []byte
+	counter uint64
+	buffer  []byte
+	closed  bool
+}
+
+// Encrypt writes a stream header for the recipients to out, and returns a
+// writer that encrypts everything written to it. The stream is only complete
+// once the writer is closed; closing does not close out.
+//
+// Recipients are RSA public keys, or ECDSA and X25519 public keys.
+func Encrypt(out io.Writer, recipients []crypto.PublicKey) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients provided")
+	}
+	fileKey := make([]byte, fileKeySize)
+	_, err := rand.Read(fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	stanzas := make([]*stanza, len(recipients))
+	for i, recipient := range recipients {
+		stanzas[i], err = wrapFileKey(recipient, fileKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = writeHeader(out, stanzas, fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	_, err = out.Write(nonce)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := payloadAEAD(fileKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writer{
+		out:    out,
+		aead:   aead,
+		nonce:  make([]byte, chunkNonceLen),
+		buffer: make([]byte, 0, chunkSize),
+	}, nil
+}
+
+func (w *writer) Write(data []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed stream")
+	}
+	written := 0
+	for len(data) > 0 {
+		if len(w.buffer) == chunkSize {
+			err := w.flush(false)
+			if err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buffer[len(w.buffer):chunkSize], data)
+		w.buffer = w.buffer[:len(w.buffer)+n]
+		data = data[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (w *writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(true)
+}
+
+func (w *writer) flush(final bool) error {
+	chunkNonce(w.nonce, w.counter, final)
+	sealed := w.aead.Seal(nil, w.nonce, w.buffer, nil)
+	_, err := w.out.Write(sealed)
+	if err != nil {
+		return err
+	}
+	w.buffer = w.buffer[:0]
+	w.counter++
+	return nil
+}
+
+func payloadAEAD(fileKey []byte, nonce []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(fileKey, nonce, "payload")
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(nonce []byte, counter uint64, final bool) {
+	for i := range nonce {
+		nonce[i] = 0
+	}
+	for i := chunkNonceLen - 2; i >= 0 && counter > 0; i-- {
+		nonce[i] = byte(counter)
+		counter >>= 8
+	}
+	if final {
+		nonce[chunkNonceLen-1] = 1
+	}
+}
@@ -0,0 +1,125 @@
+// Package tlsutil probes TLS endpoints and serves TLS for testing clients.
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// alertWait bounds the wait for an alert after a TLS 1.3 handshake.
+const alertWait = 500 * time.Millisecond
+
+// ProbeOptions configure Probe.
+type ProbeOptions struct {
+	// ServerName overrides the SNI name, which defaults to the host.
+	ServerName string
+	// Certificate is presented if the server requests a client certificate.
+	Certificate *tls.Certificate
+	NextProtos  []string
+	// Roots verify the presented chain, defaulting to the system roots.
+	Roots   *x509.CertPool
+	Timeout time.Duration
+}
+
+// ProbeResult describes a completed handshake.
+type ProbeResult struct {
+	State tls.ConnectionState
+	// VerifiedChains is empty if the presented chain failed verification,
+	// with VerifyError set.
+	VerifiedChains [][]*x509.Certificate
+	VerifyError    error
+}
+
+// Probe performs a TLS handshake with addr, accepting any presented chain,
+// then verifies the chain against the roots for the server name.
+func Probe(ctx context.Context, addr string, opts *ProbeOptions) (*ProbeResult, error) {
+	if opts == nil {
+		opts = &ProbeOptions{}
+	}
+	serverName := opts.ServerName
+	if serverName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		serverName = host
+	}
+	config := &tls.Config{
+		ServerName: serverName,
+		NextProtos: opts.NextProtos,
+		// The chain is verified after the handshake so it can be shown
+		// even when it is not trusted.
+		InsecureSkipVerify: true,
+	}
+	if opts.Certificate != nil {
+		certificate := opts.Certificate
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certificate, nil
+		}
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: opts.Timeout},
+		Config:    config,
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tlsConn := conn.(*tls.Conn)
+	err = awaitAlert(tlsConn)
+	if err != nil {
+		return nil, err
+	}
+	result := &ProbeResult{State: tlsConn.ConnectionState()}
+	result.VerifiedChains, result.VerifyError = VerifyPeer(result.State.PeerCertificates, opts.Roots, serverName, x509.ExtKeyUsageServerAuth)
+	return result, nil
+}
+
+// awaitAlert catches a TLS 1.3 server rejecting the client certificate. The
+// client handshake completes before the server checks the certificate, so
+// the rejection arrives as an alert on the first read.
+func awaitAlert(conn *tls.Conn) error {
+	if conn.ConnectionState().Version < tls.VersionTLS13 {
+		return nil
+	}
+	err := conn.SetReadDeadline(time.Now().Add(alertWait))
+	if err != nil {
+		return err
+	}
+	_, err = conn.Read(make([]byte, 1))
+	var netErr net.Error
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+		return nil
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return nil
+	default:
+		return fmt.Errorf("server rejected the connection: %w", err)
+	}
+}
+
+// VerifyPeer verifies a presented chain, leaf first, against roots. An empty
+// dnsName skips the hostname check.
+func VerifyPeer(chain []*x509.Certificate, roots *x509.CertPool, dnsName string, usage x509.ExtKeyUsage) ([][]*x509.Certificate, error) {
+	if len(chain) == 0 {
+		return nil, errNoCertificates
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	return chain[0].Verify(x509.VerifyOptions{
+		DNSName:       dnsName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+}
@@ -0,0 +1,99 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/credding/crypt/pkg/crypt"
+)
+
+func newTestCertificate(t *testing.T, issuer *crypt.Issuer, names ...string) tls.Certificate {
+	t.Helper()
+	key, cert, err := crypt.IssueDevCertificate(issuer, names, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}
+}
+
+func startServe(t *testing.T, opts *ServeOptions) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, listener, opts)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return listener.Addr().String()
+}
+
+func TestProbe(t *testing.T) {
+	issuer, err := crypt.NewDevCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(issuer.Certificate)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(issuer.Certificate)
+	serverCertificate := newTestCertificate(t, issuer, "127.0.0.1")
+	clientCertificate := newTestCertificate(t, issuer, "client.test")
+
+	addr := startServe(t, &ServeOptions{Certificate: serverCertificate})
+	requireClientAddr := startServe(t, &ServeOptions{Certificate: serverCertificate, ClientCAs: clientCAs})
+
+	tests := []struct {
+		name       string
+		addr       string
+		opts       *ProbeOptions
+		wantErr    bool
+		wantVerify bool
+	}{
+		{"trusted", addr, &ProbeOptions{Roots: roots}, false, true},
+		{"untrusted", addr, &ProbeOptions{Roots: x509.NewCertPool()}, false, false},
+		{"wrong name", addr, &ProbeOptions{Roots: roots, ServerName: "other.test"}, false, false},
+		{"client certificate", requireClientAddr, &ProbeOptions{Roots: roots, Certificate: &clientCertificate}, false, true},
+		{"missing client certificate", requireClientAddr, &ProbeOptions{Roots: roots}, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.Timeout = 5 * time.Second
+			result, err := Probe(context.Background(), test.addr, test.opts)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("Probe succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.State.Version != tls.VersionTLS13 {
+				t.Errorf("Version = %x, want TLS 1.3", result.State.Version)
+			}
+			if len(result.State.PeerCertificates) == 0 || !result.State.PeerCertificates[0].Equal(serverCertificate.Leaf) {
+				t.Error("server certificate not presented")
+			}
+			if test.wantVerify {
+				if result.VerifyError != nil || len(result.VerifiedChains) == 0 {
+					t.Errorf("VerifyError = %v, want a verified chain", result.VerifyError)
+				}
+			} else if result.VerifyError == nil {
+				t.Error("VerifyError = nil, want a verification failure")
+			}
+		})
+	}
+}
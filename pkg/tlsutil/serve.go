@@ -0,0 +1,127 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ServeOptions configure Serve.
+type ServeOptions struct {
+	Certificate tls.Certificate
+	// ClientCAs, if set, require client certificates verified against them.
+	ClientCAs *x509.CertPool
+	// RequestClientCertificate asks for a client certificate without
+	// requiring or verifying one.
+	RequestClientCertificate bool
+	NextProtos               []string
+	// Echo serves raw TLS, echoing what clients send, instead of HTTPS.
+	Echo bool
+	// Log receives a description of every handshake.
+	Log io.Writer
+}
+
+// Serve accepts TLS connections on listener until ctx is done.
+func Serve(ctx context.Context, listener net.Listener, opts *ServeOptions) error {
+	config := &tls.Config{
+		Certificates: []tls.Certificate{opts.Certificate},
+		NextProtos:   opts.NextProtos,
+	}
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		connConfig := config.Clone()
+		connConfig.GetConfigForClient = nil
+		connConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			logf(opts.Log, "%s: %s", hello.Conn.RemoteAddr(), describeConnection(&state))
+			return nil
+		}
+		return connConfig, nil
+	}
+	switch {
+	case opts.ClientCAs != nil:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = opts.ClientCAs
+	case opts.RequestClientCertificate:
+		config.ClientAuth = tls.RequestClientCert
+	}
+	tlsListener := tls.NewListener(listener, config)
+
+	if opts.Echo {
+		go func() {
+			<-ctx.Done()
+			_ = tlsListener.Close()
+		}()
+		return serveEcho(ctx, tlsListener, opts.Log)
+	}
+
+	server := &http.Server{
+		Handler:           http.HandlerFunc(describeRequest),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	err := server.Serve(tlsListener)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+func serveEcho(ctx context.Context, listener net.Listener, log io.Writer) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			err := conn.(*tls.Conn).HandshakeContext(ctx)
+			if err != nil {
+				logf(log, "%s: handshake failed: %v\n", conn.RemoteAddr(), err)
+				return
+			}
+			_, _ = io.Copy(conn, conn)
+		}()
+	}
+}
+
+func describeRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprint(w, describeConnection(r.TLS))
+}
+
+func describeConnection(state *tls.ConnectionState) string {
+	description := &strings.Builder{}
+	alpn := state.NegotiatedProtocol
+	if alpn == "" {
+		alpn = "none"
+	}
+	_, _ = fmt.Fprintf(description, "%s %s sni=%q alpn=%s\n", VersionName(state.Version),
+		tls.CipherSuiteName(state.CipherSuite), state.ServerName, alpn)
+	if len(state.PeerCertificates) == 0 {
+		_, _ = fmt.Fprintln(description, "  no client certificate")
+	}
+	for i, cert := range state.PeerCertificates {
+		_, _ = fmt.Fprintf(description, "  %d: %s\n     issuer %s\n     sha256 %s\n", i, cert.Subject, cert.Issuer, Fingerprint(cert))
+	}
+	if len(state.VerifiedChains) > 0 {
+		_, _ = fmt.Fprintln(description, "  client certificate verified")
+	}
+	return description.String()
+}
+
+func logf(log io.Writer, format string, args ...interface{}) {
+	if log != nil {
+		_, _ = fmt.Fprintf(log, format, args...)
+	}
+}